@@ -0,0 +1,124 @@
+package bitbucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	// DefaultAPIURL is the API reached through PipelinesProxy. The proxy
+	// authenticates the requests, which must be plain HTTP.
+	DefaultAPIURL = "http://api.bitbucket.org/2.0"
+	// PipelinesProxy is the authenticating proxy of Bitbucket Pipelines.
+	PipelinesProxy = "http://localhost:29418"
+)
+
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Bitbucket API HTTP code %d: %s", e.Code, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	var apiError *APIError
+	if errors.As(target, &apiError) {
+		return apiError.Code == e.Code
+	}
+
+	return false
+}
+
+// Client is a minimal Bitbucket Cloud REST API client.
+type Client struct {
+	baseURL string
+
+	httpClient *retryablehttp.Client
+}
+
+// NewClient returns a client of the API at baseURL sending the requests
+// through proxy, an empty proxy connects directly.
+func NewClient(baseURL string, proxy string) (*Client, error) {
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+
+	retryClient := retryablehttp.NewClient()
+	retryClient.Logger = nil
+	retryClient.RetryMax = 2
+	retryClient.RetryWaitMin = 1 * time.Second
+	retryClient.RetryWaitMax = 5 * time.Second
+	retryClient.HTTPClient.Timeout = 30 * time.Second
+	retryClient.CheckRetry = retryablehttp.ErrorPropagatedRetryPolicy
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse proxy URL: %w", err)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(proxyURL)
+		retryClient.HTTPClient.Transport = transport
+	}
+
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: retryClient,
+	}, nil
+}
+
+// do sends in as JSON body and decodes the response into out, when set.
+func (c *Client) do(ctx context.Context, method string, path string, in any, out any) error {
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(in); err != nil {
+		return fmt.Errorf("can't encode request body: %w", err)
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("can't create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("can't read response body: %w", err)
+		}
+
+		return &APIError{
+			Code:    resp.StatusCode,
+			Message: string(msg),
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("can't decode response: %w", err)
+	}
+
+	return nil
+}
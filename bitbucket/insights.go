@@ -0,0 +1,59 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// MaxAnnotations is the number of annotations accepted per request.
+const MaxAnnotations = 100
+
+// Report is a Code Insights report of a commit.
+type Report struct {
+	Title      string       `json:"title"`
+	Details    string       `json:"details"`
+	ReportType string       `json:"report_type"`
+	Reporter   string       `json:"reporter"`
+	Result     string       `json:"result"`
+	Data       []ReportData `json:"data"`
+}
+
+type ReportData struct {
+	Title string `json:"title"`
+	Type  string `json:"type"`
+	Value int    `json:"value"`
+}
+
+// Annotation is a finding of a Code Insights report, ExternalID identifies
+// it within the report.
+type Annotation struct {
+	ExternalID     string `json:"external_id"`
+	AnnotationType string `json:"annotation_type"`
+	Summary        string `json:"summary"`
+	Severity       string `json:"severity"`
+	Path           string `json:"path"`
+	Line           int    `json:"line,omitempty"`
+}
+
+// PutReport creates or replaces the report id of commit in the repository
+// named workspace/repo.
+func (c *Client) PutReport(ctx context.Context, repository, commit, id string, report *Report) error {
+	path := fmt.Sprintf("/repositories/%s/commit/%s/reports/%s", repository, commit, id)
+	if err := c.do(ctx, http.MethodPut, path, report, nil); err != nil {
+		return fmt.Errorf("can't put report: %w", err)
+	}
+
+	return nil
+}
+
+// CreateAnnotations adds annotations to the report id of commit, at most
+// MaxAnnotations per call.
+func (c *Client) CreateAnnotations(ctx context.Context, repository, commit, id string, annotations []Annotation) error {
+	path := fmt.Sprintf("/repositories/%s/commit/%s/reports/%s/annotations", repository, commit, id)
+	if err := c.do(ctx, http.MethodPost, path, annotations, nil); err != nil {
+		return fmt.Errorf("can't create annotations: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package ci
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/liminal-security/scan-action/github"
+)
+
+// Provider is the CI system the scanner runs in.
+type Provider string
+
+const (
+	ProviderNone      Provider = ""
+	ProviderGitHub    Provider = "github"
+	ProviderGitLab    Provider = "gitlab"
+	ProviderBitbucket Provider = "bitbucket"
+	ProviderAzure     Provider = "azure"
+)
+
+// Environment describes the CI run.
+type Environment struct {
+	Provider Provider
+	// Base and Head select the commits to scan, like git log Base..Head.
+	// They are empty when the CI system doesn't tell.
+	Base string
+	Head string
+	// MergeBase is set when Base is the tip of the target branch rather
+	// than a commit Head descends from, the scan then starts at the merge
+	// base of Base and Head.
+	MergeBase bool
+	// Repository is the web URL of the repository and PullRequest the
+	// number of the pull or merge request built, when the CI system tells.
+	Repository  string
+	PullRequest int
+}
+
+// zeroSHA is reported as the previous commit of newly pushed branches.
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// Detect inspects the environment variables set by the supported CI
+// systems.
+func Detect(getenv func(string) string) Environment {
+	switch {
+	case getenv("GITLAB_CI") == "true":
+		return gitlab(getenv)
+	case getenv("BITBUCKET_BUILD_NUMBER") != "":
+		return bitbucket(getenv)
+	case strings.EqualFold(getenv("TF_BUILD"), "true"):
+		return azure(getenv)
+	case getenv("GITHUB_ACTIONS") == "true":
+		return githubActions(getenv)
+	default:
+		return Environment{Provider: ProviderNone}
+	}
+}
+
+func githubActions(getenv func(string) string) Environment {
+	env := Environment{Provider: ProviderGitHub}
+
+	if repo := getenv("GITHUB_REPOSITORY"); repo != "" {
+		server := getenv("GITHUB_SERVER_URL")
+		if server == "" {
+			server = "https://github.com"
+		}
+		env.Repository = strings.TrimSuffix(server, "/") + "/" + repo
+	}
+
+	// Pull request workflows run for refs/pull/<number>/merge.
+	if number, ok := strings.CutPrefix(getenv("GITHUB_REF"), "refs/pull/"); ok {
+		number, _, _ = strings.Cut(number, "/")
+		env.PullRequest = pullRequest(number)
+	}
+
+	// Pull requests are scanned from the target tip they were opened or
+	// last synchronized against, falling back to the target branch. Other
+	// events scan the checked out history.
+	if event, err := github.ReadEvent(getenv("GITHUB_EVENT_PATH")); err == nil && event.PullRequest != nil {
+		env.Base = event.PullRequest.Base.SHA
+	}
+	if target := getenv("GITHUB_BASE_REF"); env.Base == "" && target != "" {
+		env.Base = "refs/remotes/origin/" + target
+	}
+	env.MergeBase = env.Base != ""
+
+	return env
+}
+
+func gitlab(getenv func(string) string) Environment {
+	env := Environment{
+		Provider:    ProviderGitLab,
+		Head:        getenv("CI_COMMIT_SHA"),
+		Base:        getenv("CI_MERGE_REQUEST_DIFF_BASE_SHA"),
+		Repository:  getenv("CI_PROJECT_URL"),
+		PullRequest: pullRequest(getenv("CI_MERGE_REQUEST_IID")),
+	}
+
+	// Branch pipelines only know the previous tip of the branch.
+	if env.Base == "" {
+		env.Base = getenv("CI_COMMIT_BEFORE_SHA")
+	}
+	if strings.Trim(env.Base, "0") == "" {
+		env.Base = ""
+	}
+
+	return env
+}
+
+func bitbucket(getenv func(string) string) Environment {
+	env := Environment{
+		Provider:    ProviderBitbucket,
+		Head:        getenv("BITBUCKET_COMMIT"),
+		Base:        getenv("BITBUCKET_PR_DESTINATION_COMMIT"),
+		Repository:  getenv("BITBUCKET_GIT_HTTP_ORIGIN"),
+		PullRequest: pullRequest(getenv("BITBUCKET_PR_ID")),
+	}
+
+	// Pull request pipelines report the tip of the destination branch,
+	// which moves on after the source branch forked.
+	env.MergeBase = env.Base != ""
+
+	return env
+}
+
+func azure(getenv func(string) string) Environment {
+	env := Environment{
+		Provider:    ProviderAzure,
+		Head:        getenv("BUILD_SOURCEVERSION"),
+		Repository:  getenv("BUILD_REPOSITORY_URI"),
+		PullRequest: pullRequest(getenv("SYSTEM_PULLREQUEST_PULLREQUESTNUMBER")),
+	}
+
+	// Azure Repos pull requests only have an ID.
+	if env.PullRequest == 0 {
+		env.PullRequest = pullRequest(getenv("SYSTEM_PULLREQUEST_PULLREQUESTID"))
+	}
+
+	// Pull request builds check out a merge commit, scan the source
+	// commits not on the target branch instead.
+	if target := getenv("SYSTEM_PULLREQUEST_TARGETBRANCH"); target != "" {
+		env.Base = "refs/remotes/origin/" + strings.TrimPrefix(target, "refs/heads/")
+		env.MergeBase = true
+		if source := getenv("SYSTEM_PULLREQUEST_SOURCECOMMITID"); source != "" {
+			env.Head = source
+		}
+	}
+
+	return env
+}
+
+// pullRequest parses a pull request number, 0 if there is none.
+func pullRequest(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0
+	}
+
+	return n
+}
@@ -0,0 +1,257 @@
+package ci
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liminal-security/scan-action/git"
+)
+
+func TestDetect(t *testing.T) {
+	event := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(event, []byte(`{"pull_request":{"number":42,"base":{"sha":"cf79a0c"},"head":{"sha":"539533a"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want Environment
+	}{
+		{
+			name: "local",
+			env:  map[string]string{},
+			want: Environment{Provider: ProviderNone},
+		},
+		{
+			name: "github actions",
+			env:  map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_SHA": "539533a"},
+			want: Environment{Provider: ProviderGitHub},
+		},
+		{
+			name: "github pull request",
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_EVENT_PATH": event,
+				"GITHUB_BASE_REF":   "main",
+			},
+			want: Environment{Provider: ProviderGitHub, Base: "cf79a0c", MergeBase: true},
+		},
+		{
+			name: "github pull request without event",
+			env:  map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_BASE_REF": "main"},
+			want: Environment{Provider: ProviderGitHub, Base: "refs/remotes/origin/main", MergeBase: true},
+		},
+		{
+			name: "gitlab merge request",
+			env: map[string]string{
+				"GITLAB_CI":                      "true",
+				"CI_COMMIT_SHA":                  "539533a",
+				"CI_COMMIT_BEFORE_SHA":           zeroSHA,
+				"CI_MERGE_REQUEST_DIFF_BASE_SHA": "cf79a0c",
+			},
+			want: Environment{Provider: ProviderGitLab, Base: "cf79a0c", Head: "539533a"},
+		},
+		{
+			name: "gitlab branch pipeline",
+			env: map[string]string{
+				"GITLAB_CI":            "true",
+				"CI_COMMIT_SHA":        "539533a",
+				"CI_COMMIT_BEFORE_SHA": "9006ae9",
+			},
+			want: Environment{Provider: ProviderGitLab, Base: "9006ae9", Head: "539533a"},
+		},
+		{
+			name: "gitlab new branch",
+			env: map[string]string{
+				"GITLAB_CI":            "true",
+				"CI_COMMIT_SHA":        "539533a",
+				"CI_COMMIT_BEFORE_SHA": zeroSHA,
+			},
+			want: Environment{Provider: ProviderGitLab, Head: "539533a"},
+		},
+		{
+			name: "bitbucket pull request",
+			env: map[string]string{
+				"BITBUCKET_BUILD_NUMBER":          "42",
+				"BITBUCKET_COMMIT":                "539533a",
+				"BITBUCKET_PR_DESTINATION_COMMIT": "cf79a0c",
+			},
+			want: Environment{Provider: ProviderBitbucket, Base: "cf79a0c", Head: "539533a", MergeBase: true},
+		},
+		{
+			name: "bitbucket branch pipeline",
+			env:  map[string]string{"BITBUCKET_BUILD_NUMBER": "42", "BITBUCKET_COMMIT": "539533a"},
+			want: Environment{Provider: ProviderBitbucket, Head: "539533a"},
+		},
+		{
+			name: "azure pull request",
+			env: map[string]string{
+				"TF_BUILD":                          "True",
+				"BUILD_SOURCEVERSION":               "f00dbabe",
+				"SYSTEM_PULLREQUEST_TARGETBRANCH":   "refs/heads/main",
+				"SYSTEM_PULLREQUEST_SOURCECOMMITID": "539533a",
+			},
+			want: Environment{Provider: ProviderAzure, Base: "refs/remotes/origin/main", Head: "539533a", MergeBase: true},
+		},
+		{
+			name: "azure branch build",
+			env:  map[string]string{"TF_BUILD": "True", "BUILD_SOURCEVERSION": "539533a"},
+			want: Environment{Provider: ProviderAzure, Head: "539533a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			assert.Equal(t, tt.want, Detect(getenv))
+		})
+	}
+}
+
+func TestDetectProvenance(t *testing.T) {
+	tests := []struct {
+		name            string
+		env             map[string]string
+		wantRepository  string
+		wantPullRequest int
+	}{
+		{
+			name: "github pull request",
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_SERVER_URL": "https://github.example.com/",
+				"GITHUB_REPOSITORY": "liminal-security/scan-action",
+				"GITHUB_REF":        "refs/pull/42/merge",
+			},
+			wantRepository:  "https://github.example.com/liminal-security/scan-action",
+			wantPullRequest: 42,
+		},
+		{
+			name:           "github push",
+			env:            map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_REPOSITORY": "liminal-security/scan-action", "GITHUB_REF": "refs/heads/main"},
+			wantRepository: "https://github.com/liminal-security/scan-action",
+		},
+		{
+			name:            "gitlab merge request",
+			env:             map[string]string{"GITLAB_CI": "true", "CI_PROJECT_URL": "https://gitlab.com/liminal/app", "CI_MERGE_REQUEST_IID": "7"},
+			wantRepository:  "https://gitlab.com/liminal/app",
+			wantPullRequest: 7,
+		},
+		{
+			name:            "bitbucket pull request",
+			env:             map[string]string{"BITBUCKET_BUILD_NUMBER": "1", "BITBUCKET_GIT_HTTP_ORIGIN": "http://bitbucket.org/liminal/app", "BITBUCKET_PR_ID": "3"},
+			wantRepository:  "http://bitbucket.org/liminal/app",
+			wantPullRequest: 3,
+		},
+		{
+			name:            "azure repos pull request",
+			env:             map[string]string{"TF_BUILD": "True", "BUILD_REPOSITORY_URI": "https://dev.azure.com/liminal/_git/app", "SYSTEM_PULLREQUEST_PULLREQUESTID": "12"},
+			wantRepository:  "https://dev.azure.com/liminal/_git/app",
+			wantPullRequest: 12,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(func(key string) string { return tt.env[key] })
+
+			assert.Equal(t, tt.wantRepository, got.Repository)
+			assert.Equal(t, tt.wantPullRequest, got.PullRequest)
+		})
+	}
+}
+
+func TestDetectDivergedDestination(t *testing.T) {
+	dir := t.TempDir()
+	gitCmd := func(args ...string) string {
+		t.Helper()
+
+		cmd := exec.Command("/usr/bin/git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("can't run %s: %s\nOutput:\n%s", cmd.String(), err, out)
+		}
+
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(name string) string {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		gitCmd("add", name)
+		gitCmd("commit", "--quiet", "-m", name)
+
+		return gitCmd("rev-parse", "HEAD")
+	}
+
+	// The destination branch moved on, and the source branch merged an
+	// older state of it, after the source branch forked.
+	gitCmd("init", "--quiet", "--initial-branch=main")
+	commit("a.txt")
+	gitCmd("checkout", "--quiet", "-b", "feature")
+	f1 := commit("f1.txt")
+	gitCmd("checkout", "--quiet", "main")
+	commit("b.txt")
+	gitCmd("checkout", "--quiet", "feature")
+	gitCmd("merge", "--quiet", "--no-ff", "-m", "merge main", "main")
+	merge := gitCmd("rev-parse", "HEAD")
+	gitCmd("checkout", "--quiet", "main")
+	commit("c.txt")
+	dest := gitCmd("rev-parse", "HEAD")
+	gitCmd("checkout", "--quiet", "feature")
+	gitCmd("update-ref", "refs/remotes/origin/main", dest)
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "bitbucket",
+			env: map[string]string{
+				"BITBUCKET_BUILD_NUMBER":          "42",
+				"BITBUCKET_COMMIT":                merge,
+				"BITBUCKET_PR_DESTINATION_COMMIT": dest,
+			},
+			want: []string{merge, f1},
+		},
+		{
+			name: "azure",
+			env: map[string]string{
+				"TF_BUILD":                          "True",
+				"SYSTEM_PULLREQUEST_TARGETBRANCH":   "refs/heads/main",
+				"SYSTEM_PULLREQUEST_SOURCECOMMITID": merge,
+			},
+			want: []string{merge, f1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := Detect(func(key string) string { return tt.env[key] })
+			assert.True(t, env.MergeBase)
+
+			differ, err := git.NewDiffer(dir, nil)
+			if err != nil {
+				t.Fatalf("can't create differ: %s", err)
+			}
+			base, err := differ.MergeBase(env.Base, env.Head)
+			if err != nil {
+				t.Fatalf("MergeBase() error = %s", err)
+			}
+			commits, err := differ.DiffRange(git.Range{Base: base, Head: env.Head})
+			if err != nil {
+				t.Fatalf("DiffRange() error = %s", err)
+			}
+
+			var got []string
+			for _, commit := range commits {
+				got = append(got, commit.Hash)
+			}
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Package cli implements the scan-action command line.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/liminal-security/scan-action/policy"
+)
+
+// Version is set at build time with
+// -ldflags "-X github.com/liminal-security/scan-action/cli.Version=v1.2.3".
+var Version = "dev"
+
+// command is a subcommand of scan-action.
+type command struct {
+	name        string
+	args        string
+	description string
+	// setup registers the flags of the command.
+	setup func(fs *flagSet, cfg *config)
+	run   func(a *app, cfg *config, fs *flagSet) int
+}
+
+// commands returns the subcommands, scan first as it is the default one.
+func commands() []command {
+	return []command{
+		{
+			name:        "scan",
+			args:        "[flags] [<git repo>]",
+			description: "Scan the commits of the pull request, merge request or push the CI runs for, or the --base..--head range.",
+			setup: func(fs *flagSet, cfg *config) {
+				registerConfig(fs, cfg)
+				fs.StringVar(&cfg.Base, "base", "", "scan the commits after this revision, overrides the CI environment")
+				fs.StringVar(&cfg.Head, "head", "", "scan the commits up to this revision, defaults to HEAD")
+				fs.StringVar(&cfg.Patch, "patch", "", "scan the unified diff or git format-patch series in this file instead of a repository, - reads stdin")
+			},
+			run: func(a *app, cfg *config, fs *flagSet) int {
+				return a.scan(cfg, fs, modeScan)
+			},
+		},
+		{
+			name:        "audit",
+			args:        "[flags] [<git repo>]",
+			description: "Scan the whole history reachable from --head, regardless of the CI environment.",
+			setup:       setupHistory,
+			run: func(a *app, cfg *config, fs *flagSet) int {
+				return a.scan(cfg, fs, modeAudit)
+			},
+		},
+		{
+			name:        "baseline",
+			args:        "[flags] [<git repo>]",
+			description: "Write the secrets in the whole history to the --baseline file, " + defaultBaseline + " by default, later scans with --baseline ignore them.",
+			setup:       setupHistory,
+			run: func(a *app, cfg *config, fs *flagSet) int {
+				return a.scan(cfg, fs, modeBaseline)
+			},
+		},
+		{
+			name:        "hook",
+			args:        "[flags] [<git repo>]",
+			description: "Print a git pre-push hook scanning the pushed commits, or install it with --install.",
+			setup: func(fs *flagSet, cfg *config) {
+				fs.BoolVar(&cfg.hook.install, "install", false, "install the hook into the repository")
+				fs.BoolVar(&cfg.hook.force, "force", false, "overwrite an existing pre-push hook")
+				fs.StringVar(&cfg.hook.command, "command", "scan-action", "command the hook runs")
+			},
+			run: (*app).hook,
+		},
+		{
+			name:        "config",
+			args:        "[flags]",
+			description: "Print the effective configuration, every flag with its environment variable and value.",
+			setup:       registerConfig,
+			run:         (*app).config,
+		},
+		{
+			name:        "completion",
+			args:        "bash|zsh|fish",
+			description: "Print the shell completion script, e.g. source <(scan-action completion bash).",
+			run:         (*app).completion,
+		},
+		{
+			name:        "version",
+			args:        "",
+			description: "Print the version.",
+			run: func(a *app, _ *config, _ *flagSet) int {
+				fmt.Fprintf(a.stdout, "scan-action %s\n", version())
+				return policy.ExitOK
+			},
+		},
+	}
+}
+
+func setupHistory(fs *flagSet, cfg *config) {
+	registerConfig(fs, cfg)
+	fs.StringVar(&cfg.Head, "head", "", "scan the commits up to this revision, defaults to HEAD")
+}
+
+// app runs commands, its environment is swappable for tests.
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	getenv func(string) string
+}
+
+// Run runs scan-action with args, without the program name, and returns
+// the exit code.
+func Run(args []string) int {
+	a := &app{stdin: os.Stdin, stdout: os.Stdout, getenv: os.Getenv}
+
+	return a.run(args)
+}
+
+func (a *app) run(args []string) int {
+	if len(args) == 0 {
+		a.usage()
+		return policy.ExitConfig
+	}
+
+	switch args[0] {
+	case "-h", "-help", "--help":
+		a.usage()
+		return policy.ExitOK
+	case "help":
+		if len(args) == 1 {
+			a.usage()
+			return policy.ExitOK
+		}
+
+		return a.run([]string{args[1], "--help"})
+	}
+
+	for _, cmd := range commands() {
+		if cmd.name == args[0] {
+			return a.runCommand(cmd, args[1:])
+		}
+	}
+
+	// scan-action [flags] <git repo> predates the subcommands.
+	if strings.HasPrefix(args[0], "-") || isDir(args[0]) {
+		return a.runCommand(commands()[0], args)
+	}
+
+	fmt.Fprintf(a.stdout, "Error: unknown command %q\n\n", args[0])
+	a.usage()
+
+	return policy.ExitConfig
+}
+
+func (a *app) runCommand(cmd command, args []string) int {
+	cfg := &config{}
+	fs := a.flagSet(cmd)
+	if cmd.setup != nil {
+		cmd.setup(fs, cfg)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return policy.ExitOK
+		}
+
+		return policy.ExitConfig
+	}
+
+	return cmd.run(a, cfg, fs)
+}
+
+func (a *app) flagSet(cmd command) *flagSet {
+	fs := &flagSet{
+		FlagSet: flag.NewFlagSet(cmd.name, flag.ContinueOnError),
+		getenv:  a.getenv,
+		envs:    map[string]string{},
+		secrets: map[string]bool{},
+	}
+	fs.SetOutput(a.stdout)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stdout, "Usage: scan-action %s %s\n\n%s\n", cmd.name, cmd.args, cmd.description)
+
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(a.stdout, "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+
+	return fs
+}
+
+func (a *app) usage() {
+	fmt.Fprintln(a.stdout, "Usage: scan-action <command> [flags] [args]")
+	fmt.Fprintln(a.stdout)
+	fmt.Fprintln(a.stdout, "Commands:")
+
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %s\t%s\n", cmd.name, cmd.description)
+	}
+	w.Flush()
+
+	fmt.Fprintln(a.stdout)
+	fmt.Fprintln(a.stdout, `Run "scan-action help <command>" for the flags of a command.`)
+	fmt.Fprintln(a.stdout, "Every flag defaults to the ENTRO_* environment variable named in its help.")
+}
+
+// config prints the settings of fs.
+func (a *app) config(_ *config, fs *flagSet) int {
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return policy.ExitConfig
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FLAG\tENVIRONMENT\tVALUE")
+	for _, s := range fs.settings() {
+		fmt.Fprintf(w, "--%s\t%s\t%s\n", s.Flag, s.Env, s.Value)
+	}
+	w.Flush()
+
+	return policy.ExitOK
+}
+
+func version() string {
+	if Version != "dev" {
+		return Version
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+
+	return Version
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+
+	return err == nil && info.IsDir()
+}
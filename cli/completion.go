@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/liminal-security/scan-action/policy"
+)
+
+var shells = []string{"bash", "zsh", "fish"}
+
+// completion prints the completion script of a shell.
+func (a *app) completion(_ *config, fs *flagSet) int {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return policy.ExitConfig
+	}
+
+	switch fs.Arg(0) {
+	case "bash":
+		a.writeBash(a.stdout)
+	case "zsh":
+		// zsh runs bash completions through bashcompinit.
+		fmt.Fprintln(a.stdout, "autoload -U +X bashcompinit && bashcompinit")
+		a.writeBash(a.stdout)
+	case "fish":
+		a.writeFish(a.stdout)
+	default:
+		fmt.Fprintf(a.stdout, "Error: unknown shell %q, expected %s\n", fs.Arg(0), strings.Join(shells, ", "))
+		return policy.ExitConfig
+	}
+
+	return policy.ExitOK
+}
+
+// commandFlags returns the flag names of every command.
+func (a *app) commandFlags() map[string][]string {
+	flags := map[string][]string{}
+	for _, cmd := range commands() {
+		fs := a.flagSet(cmd)
+		if cmd.setup != nil {
+			cmd.setup(fs, &config{})
+		}
+
+		names := []string{"--help"}
+		fs.VisitAll(func(fl *flag.Flag) {
+			names = append(names, "--"+fl.Name)
+		})
+		flags[cmd.name] = names
+	}
+
+	return flags
+}
+
+func (a *app) writeBash(w io.Writer) {
+	var names []string
+	for _, cmd := range commands() {
+		names = append(names, cmd.name)
+	}
+	flags := a.commandFlags()
+
+	fmt.Fprintln(w, "_scan_action() {")
+	fmt.Fprintln(w, `	local cur=${COMP_WORDS[COMP_CWORD]} words`)
+	fmt.Fprintln(w, `	if [ "$COMP_CWORD" -eq 1 ]; then`)
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", quote(strings.Join(append(names, "help"), " ")))
+	fmt.Fprintln(w, "\t\treturn")
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintln(w, `	case ${COMP_WORDS[1]} in`)
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "\t%s) words=%s ;;\n", cmd.name, quote(strings.Join(flags[cmd.name], " ")))
+	}
+	fmt.Fprintf(w, "\thelp) words=%s ;;\n", quote(strings.Join(names, " ")))
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintln(w, `	case ${COMP_WORDS[1]}:$cur in`)
+	fmt.Fprintf(w, "\tcompletion:*) COMPREPLY=($(compgen -W %s -- \"$cur\")) ;;\n", quote(strings.Join(shells, " ")))
+	fmt.Fprintln(w, `	help:* | *:-*) COMPREPLY=($(compgen -W "$words" -- "$cur")) ;;`)
+	fmt.Fprintln(w, `	*) COMPREPLY=($(compgen -d -- "$cur")) ;;`)
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -F _scan_action scan-action")
+}
+
+func (a *app) writeFish(w io.Writer) {
+	fmt.Fprintln(w, "complete -c scan-action -f")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "complete -c scan-action -n __fish_use_subcommand -a %s -d %s\n", cmd.name, quote(cmd.description))
+	}
+	fmt.Fprintf(w, "complete -c scan-action -n '__fish_seen_subcommand_from completion' -a %s\n", quote(strings.Join(shells, " ")))
+
+	for _, cmd := range commands() {
+		fs := a.flagSet(cmd)
+		if cmd.setup != nil {
+			cmd.setup(fs, &config{})
+		}
+		fs.VisitAll(func(fl *flag.Flag) {
+			fmt.Fprintf(w, "complete -c scan-action -n '__fish_seen_subcommand_from %s' -l %s -d %s\n", cmd.name, fl.Name, quote(fl.Usage))
+		})
+	}
+}
+
+// quote quotes s for a shell single quoted string.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+
+	"github.com/liminal-security/scan-action/verify"
+)
+
+// config holds the settings of the scanning commands. Every setting is a
+// flag defaulting to its ENTRO_* environment variable.
+type config struct {
+	APIEndpoint   string
+	Token         string
+	FailOnError   bool
+	Debug         bool
+	ScanGenerics  bool
+	OutputJSON    string
+	OutputJUnit   string
+	OutputSARIF   string
+	FailOn        string
+	SeverityRules string
+	PRComments    bool
+	Checks        bool
+	CodeInsights  bool
+	GitLabReport  string
+	Format        string
+	LogLevel      string
+	LogFormat     string
+	MaskVisible   string
+	Baseline      string
+	CacheDir      string
+	CacheKey      string
+	CacheMaxAge   string
+	Deepen        bool
+	Remote        string
+
+	Verify          bool
+	VerifiedOnly    bool
+	VerifyGitHubURL string
+	VerifySlackURL  string
+	VerifyBasicURL  string
+
+	Provenance bool
+	Batch      bool
+
+	CAFiles       string
+	ClientCert    string
+	ClientKey     string
+	MinTLSVersion string
+
+	// Base and Head override the range detected from the CI environment.
+	Base string
+	Head string
+	// Patch is a diff scanned instead of the repository, - for stdin.
+	Patch string
+
+	hook hookConfig
+}
+
+// setting is a flag mirroring an environment variable.
+type setting struct {
+	Flag  string
+	Env   string
+	Value string
+}
+
+// flagSet is a flag set whose flags default to environment variables.
+type flagSet struct {
+	*flag.FlagSet
+
+	getenv  func(string) string
+	envs    map[string]string
+	secrets map[string]bool
+}
+
+func (f *flagSet) stringVar(p *string, name, env, fallback, usage string) {
+	value := f.getenv(env)
+	if value == "" {
+		value = fallback
+	}
+
+	f.StringVar(p, name, value, fmt.Sprintf("%s (%s)", usage, env))
+	f.envs[name] = env
+}
+
+// secretVar is a stringVar whose value is never printed.
+func (f *flagSet) secretVar(p *string, name, env, usage string) {
+	f.stringVar(p, name, env, "", usage)
+	f.secrets[name] = true
+}
+
+func (f *flagSet) boolVar(p *bool, name, env, usage string) {
+	f.BoolVar(p, name, f.getenv(env) == "true", fmt.Sprintf("%s (%s)", usage, env))
+	f.envs[name] = env
+}
+
+// settings returns the current value of every flag mirroring an environment
+// variable, sorted by flag name. Secrets are reported by length only.
+func (f *flagSet) settings() []setting {
+	var settings []setting
+	f.VisitAll(func(fl *flag.Flag) {
+		env, ok := f.envs[fl.Name]
+		if !ok {
+			return
+		}
+
+		value := fl.Value.String()
+		if f.secrets[fl.Name] {
+			value = secretValue(value)
+		}
+		settings = append(settings, setting{Flag: fl.Name, Env: env, Value: value})
+	})
+
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Flag < settings[j].Flag
+	})
+
+	return settings
+}
+
+func secretValue(value string) string {
+	if value == "" {
+		return "[NOT SET]"
+	}
+
+	return fmt.Sprintf("[SET] (length: %d)", len(value))
+}
+
+// registerConfig registers the flags shared by the scanning commands.
+func registerConfig(fs *flagSet, cfg *config) {
+	fs.stringVar(&cfg.APIEndpoint, "api-endpoint", "ENTRO_API_ENDPOINT", "", "Entro API endpoint")
+	fs.secretVar(&cfg.Token, "token", "ENTRO_TOKEN", "Entro API token, prefer the environment variable over the flag")
+	fs.boolVar(&cfg.FailOnError, "fail-on-error", "ENTRO_FAIL_ON_ERROR", "stop and fail at the first API error")
+	fs.boolVar(&cfg.Debug, "debug", "ENTRO_DEBUG", "debug logging, same as --log-level debug")
+	fs.boolVar(&cfg.ScanGenerics, "scan-generics", "ENTRO_SCAN_GENERICS", "also scan for generic secrets like high entropy strings")
+	fs.stringVar(&cfg.OutputJSON, "output-json", "ENTRO_OUTPUT_JSON", "", "write the findings as JSON to this path")
+	fs.stringVar(&cfg.OutputJUnit, "output-junit", "ENTRO_OUTPUT_JUNIT", "", "write the findings as JUnit XML to this path")
+	fs.stringVar(&cfg.OutputSARIF, "output-sarif", "ENTRO_OUTPUT_SARIF", "", "write the findings as SARIF to this path")
+	fs.stringVar(&cfg.FailOn, "fail-on", "ENTRO_FAIL_ON", "low", "lowest severity failing the scan: info, low, medium, high or critical")
+	fs.stringVar(&cfg.SeverityRules, "severity", "ENTRO_SEVERITY_RULES", "", `comma separated ORIGIN_PATTERN=SEVERITY rules, e.g. "GENERIC*=low"`)
+	fs.boolVar(&cfg.PRComments, "pr-comments", "ENTRO_PR_COMMENTS", "post findings as pull request review comments, needs GITHUB_TOKEN")
+	fs.boolVar(&cfg.Checks, "checks", "ENTRO_CHECKS", "report findings as a check run with annotations, needs GITHUB_TOKEN")
+	fs.boolVar(&cfg.CodeInsights, "code-insights", "ENTRO_CODE_INSIGHTS", "upload the Bitbucket Code Insights report and annotations through the Pipelines proxy")
+	fs.stringVar(&cfg.GitLabReport, "gitlab-report", "ENTRO_GITLAB_REPORT", "", "write a GitLab secret detection report to this path, defaults to gl-secret-detection-report.json in GitLab CI")
+	fs.stringVar(&cfg.Format, "format", "ENTRO_FORMAT", "auto", "output format: auto, text, github, gitlab, bitbucket or azure, auto picks the one of the detected CI")
+	fs.stringVar(&cfg.LogLevel, "log-level", "ENTRO_LOG_LEVEL", "", "log level: debug, info, warn or error, defaults to info")
+	fs.stringVar(&cfg.LogFormat, "log-format", "ENTRO_LOG_FORMAT", "text", "log format: text or json")
+	fs.stringVar(&cfg.MaskVisible, "mask-visible", "ENTRO_MASK_VISIBLE", "4", "leading characters of a secret left visible in the output, at most a quarter of it")
+	fs.stringVar(&cfg.CacheDir, "cache-dir", "ENTRO_CACHE_DIR", "", "cache scan responses in this directory, unchanged commits aren't sent to the API again")
+	fs.stringVar(&cfg.CacheKey, "cache-key", "ENTRO_CACHE_KEY", "", "change it to invalidate the cached responses when the API rules change, the API doesn't report their version")
+	fs.stringVar(&cfg.CacheMaxAge, "cache-max-age", "ENTRO_CACHE_MAX_AGE", "168h", "rescan commits whose cached responses are older, 0 keeps them forever")
+	fs.stringVar(&cfg.Baseline, "baseline", "ENTRO_BASELINE", "", "ignore the secrets listed in this baseline file, see the baseline command")
+	fs.boolVar(&cfg.Deepen, "deepen", "ENTRO_DEEPEN", "fetch more history from --remote when a shallow checkout lacks the scanned range")
+	fs.stringVar(&cfg.Remote, "remote", "ENTRO_REMOTE", "origin", "remote fetched from by --deepen")
+	fs.boolVar(&cfg.Provenance, "provenance", "ENTRO_PROVENANCE", "send the repository, commit, author, files and pull request of every scan so findings show up in the Entro inventory")
+	fs.stringVar(&cfg.CAFiles, "ca-file", "ENTRO_CA_FILE", "", "comma separated PEM files of CAs trusted on top of the system ones, e.g. of a TLS inspecting proxy")
+	fs.stringVar(&cfg.ClientCert, "client-cert", "ENTRO_CLIENT_CERT", "", "PEM client certificate for API endpoints requiring mutual TLS")
+	fs.stringVar(&cfg.ClientKey, "client-key", "ENTRO_CLIENT_KEY", "", "PEM key of --client-cert, defaults to the --client-cert file")
+	fs.stringVar(&cfg.MinTLSVersion, "min-tls-version", "ENTRO_MIN_TLS_VERSION", "1.2", "lowest TLS version accepted from the API endpoint: 1.2 or 1.3")
+	fs.boolVar(&cfg.Batch, "batch", "ENTRO_BATCH", "scan small commits together in batch requests to the API")
+	fs.boolVar(&cfg.Verify, "verify", "ENTRO_VERIFY", "check whether found GitHub and Slack tokens and basic credentials are live")
+	fs.boolVar(&cfg.VerifiedOnly, "verified-only", "ENTRO_VERIFIED_ONLY", "only fail on secrets verified to be live, needs --verify")
+	fs.stringVar(&cfg.VerifyGitHubURL, "verify-github-url", "ENTRO_VERIFY_GITHUB_URL", verify.DefaultGitHubURL, "GitHub API checking tokens with GET /user")
+	fs.stringVar(&cfg.VerifySlackURL, "verify-slack-url", "ENTRO_VERIFY_SLACK_URL", verify.DefaultSlackURL, "Slack API checking tokens with auth.test")
+	fs.stringVar(&cfg.VerifyBasicURL, "verify-basic-url", "ENTRO_VERIFY_BASIC_URL", "", "URL checking *BASIC_AUTH* credentials with a GET, they aren't checked when empty")
+}
+
+// logLevel resolves the log level, --debug keeps working.
+func (c *config) logLevel() string {
+	switch {
+	case c.LogLevel != "":
+		return c.LogLevel
+	case c.Debug:
+		return "debug"
+	default:
+		return "info"
+	}
+}
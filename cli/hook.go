@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/liminal-security/scan-action/policy"
+)
+
+type hookConfig struct {
+	install bool
+	force   bool
+	command string
+}
+
+// prePushHook scans the commits of every pushed ref not yet on the remote,
+// the ENTRO_* environment variables configure the scan.
+var prePushHook = template.Must(template.New("pre-push").Parse(`#!/bin/sh
+# Installed by scan-action hook, scans the pushed commits for secrets.
+# Deleted and new refs have an all zeros hash.
+is_zero() {
+	case $1 in *[!0]*) return 1 ;; esac
+}
+remote=$1
+while read -r local_ref local_sha remote_ref remote_sha; do
+	if is_zero "$local_sha"; then
+		continue
+	fi
+	if is_zero "$remote_sha"; then
+		# New branches are scanned from the newest commit the remote has.
+		if [ -z "$(git rev-list -n 1 "$local_sha" --not --remotes="$remote")" ]; then
+			continue
+		fi
+		base=$(git rev-list --boundary "$local_sha" --not --remotes="$remote" | sed -n 's/^-//p' | head -n 1)
+		if [ -n "$base" ]; then
+			{{.}} scan --base "$base" --head "$local_sha" . || exit $?
+		else
+			{{.}} scan --head "$local_sha" . || exit $?
+		fi
+	else
+		{{.}} scan --base "$remote_sha" --head "$local_sha" . || exit $?
+	fi
+done
+`))
+
+// hook prints or installs the pre-push hook.
+func (a *app) hook(cfg *config, fs *flagSet) int {
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return policy.ExitConfig
+	}
+
+	var script bytes.Buffer
+	if err := prePushHook.Execute(&script, quote(cfg.hook.command)); err != nil {
+		fmt.Fprintf(a.stdout, "Error: can't render hook: %s\n", err)
+		return policy.ExitError
+	}
+
+	if !cfg.hook.install {
+		a.stdout.Write(script.Bytes())
+		return policy.ExitOK
+	}
+
+	repoPath := "."
+	if fs.NArg() == 1 {
+		repoPath = fs.Arg(0)
+	}
+
+	path, err := installHook(repoPath, script.Bytes(), cfg.hook.force)
+	if err != nil {
+		fmt.Fprintf(a.stdout, "Error: %s\n", err)
+		return policy.ExitError
+	}
+
+	fmt.Fprintf(a.stdout, "Installed %s\n", path)
+
+	return policy.ExitOK
+}
+
+// installHook writes the hook to the hooks directory git uses for the
+// repository, which honors core.hooksPath and the git directory of
+// worktrees and submodules.
+func installHook(repoPath string, script []byte, force bool) (string, error) {
+	out, err := exec.Command("git", "-C", repoPath, "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not a git repository: %w", repoPath, err)
+	}
+	hooksDir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(hooksDir) {
+		hooksDir = filepath.Join(repoPath, hooksDir)
+	}
+
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		return "", fmt.Errorf("can't create hooks directory: %w", err)
+	}
+
+	path := filepath.Join(hooksDir, "pre-push")
+	if _, err := os.Stat(path); err == nil && !force {
+		return "", fmt.Errorf("%s exists, use --force to overwrite it", path)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("can't check %s: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, script, 0o755); err != nil {
+		return "", fmt.Errorf("can't write hook: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file.
+	if err := os.Chmod(path, 0o755); err != nil {
+		return "", fmt.Errorf("can't make hook executable: %w", err)
+	}
+
+	return path, nil
+}
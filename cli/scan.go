@@ -0,0 +1,569 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liminal-security/scan-action/bitbucket"
+	"github.com/liminal-security/scan-action/ci"
+	"github.com/liminal-security/scan-action/entro"
+	"github.com/liminal-security/scan-action/findings"
+	"github.com/liminal-security/scan-action/git"
+	"github.com/liminal-security/scan-action/github"
+	"github.com/liminal-security/scan-action/logging"
+	"github.com/liminal-security/scan-action/policy"
+	"github.com/liminal-security/scan-action/redact"
+	"github.com/liminal-security/scan-action/report"
+	"github.com/liminal-security/scan-action/scan"
+	"github.com/liminal-security/scan-action/verify"
+)
+
+// defaultBaseline is the file the baseline command writes by default.
+const defaultBaseline = ".entro-baseline.json"
+
+// mode is what the scanning commands scan and do with the result.
+type mode int
+
+const (
+	modeScan mode = iota
+	modeAudit
+	modeBaseline
+)
+
+// scan runs the scan, audit and baseline commands.
+func (a *app) scan(cfg *config, fs *flagSet, mode mode) int { //nolint:funlen,gocognit,gocyclo
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return policy.ExitConfig
+	}
+
+	// Like git, GIT_DIR overrides the repository in the working directory.
+	repoPath := "."
+	if gitDir := a.getenv("GIT_DIR"); gitDir != "" {
+		repoPath = gitDir
+	}
+	if fs.NArg() == 1 {
+		repoPath = fs.Arg(0)
+	}
+
+	logLevel, err := logging.ParseLevel(cfg.logLevel())
+	if err != nil {
+		fmt.Fprintf(a.stdout, "Error: invalid --log-level: %s\n", err)
+		return policy.ExitConfig
+	}
+
+	logFormat, err := logging.ParseFormat(cfg.LogFormat)
+	if err != nil {
+		fmt.Fprintf(a.stdout, "Error: invalid --log-format: %s\n", err)
+		return policy.ExitConfig
+	}
+
+	maskVisible, err := strconv.Atoi(cfg.MaskVisible)
+	if err != nil || maskVisible < 0 {
+		fmt.Fprintf(a.stdout, "Error: invalid --mask-visible %q, expected a number of characters\n", cfg.MaskVisible)
+		return policy.ExitConfig
+	}
+
+	inGitHub := a.getenv("GITHUB_ACTIONS") == "true"
+
+	// Everything goes through the redactor, secrets are registered with it
+	// (and GitHub) as soon as they are known.
+	var addMask io.Writer
+	if inGitHub {
+		addMask = a.stdout
+	}
+	redactor := redact.New(maskVisible, addMask)
+	redactor.Add(cfg.Token, a.getenv("GITHUB_TOKEN"))
+	stdout := redactor.Writer(a.stdout)
+
+	logger := logging.New(stdout, logLevel, logFormat, inGitHub)
+
+	failOn, err := findings.ParseSeverity(cfg.FailOn)
+	if err != nil {
+		logger.Error("invalid --fail-on", "error", err)
+		return policy.ExitConfig
+	}
+
+	rules, err := policy.ParseRules(cfg.SeverityRules)
+	if err != nil {
+		logger.Error("invalid --severity", "error", err)
+		return policy.ExitConfig
+	}
+
+	if cfg.VerifiedOnly && !cfg.Verify {
+		logger.Error("--verified-only needs --verify")
+		return policy.ExitConfig
+	}
+
+	scanPolicy := policy.New(rules, failOn)
+	scanPolicy.VerifiedOnly = cfg.VerifiedOnly
+
+	format, err := report.ParseFormat(cfg.Format)
+	if err != nil {
+		logger.Error("invalid --format", "error", err)
+		return policy.ExitConfig
+	}
+
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		attrs := []any{}
+		for _, s := range fs.settings() {
+			attrs = append(attrs, s.Env, s.Value)
+		}
+		logger.Debug("configuration", attrs...)
+	}
+
+	// Validate configuration
+	if cfg.APIEndpoint == "" || cfg.Token == "" {
+		logger.Error("the API endpoint and token are required, set ENTRO_API_ENDPOINT and ENTRO_TOKEN",
+			"endpoint_set", cfg.APIEndpoint != "", "token_set", cfg.Token != "")
+		if inGitHub {
+			fmt.Fprintf(stdout, "Your GitHub secret may exist but have no value, or the secret name doesn't match.\n")
+			fmt.Fprintf(stdout, "\nCheck:\n")
+			fmt.Fprintf(stdout, "  1. Secret exists in: Settings → Secrets and variables → Actions\n")
+			fmt.Fprintf(stdout, "  2. Secret has a value (not empty)\n")
+			fmt.Fprintf(stdout, "  3. Secret name in workflow matches exactly (case-sensitive)\n")
+		}
+
+		return policy.ExitConfig
+	}
+
+	// Validate URL format
+	if _, err := url.Parse(cfg.APIEndpoint); err != nil {
+		logger.Error("invalid API endpoint URL", "endpoint", cfg.APIEndpoint, "error", err)
+		return policy.ExitConfig
+	}
+
+	// Show token info (length only, not the actual token)
+	logger.Info("configuration", "endpoint", cfg.APIEndpoint, "token_length", len(cfg.Token))
+
+	if cfg.FailOnError {
+		logger.Info("strict mode: will fail on API errors")
+	}
+
+	var filters []scan.Filter
+	if cfg.Baseline != "" && mode != modeBaseline {
+		baseline, err := findings.ReadBaseline(cfg.Baseline)
+		if err != nil {
+			logger.Error("invalid --baseline", "error", err)
+			return policy.ExitConfig
+		}
+		logger.Info("ignoring baseline secrets", "path", cfg.Baseline, "count", len(baseline.Secrets))
+		filters = append(filters, func(f findings.Finding) bool {
+			return !baseline.Contains(f)
+		})
+	}
+
+	clientOptions, err := a.clientOptions(cfg)
+	if err != nil {
+		logger.Error("invalid API client settings", "error", err)
+		return policy.ExitConfig
+	}
+	entroClient, err := entro.NewClient(cfg.APIEndpoint, cfg.Token, append(clientOptions, entro.WithLogger(logger), entro.WithGenerics(cfg.ScanGenerics))...)
+	if err != nil {
+		logger.Error("can't create API client", "error", err)
+		return policy.ExitConfig
+	}
+
+	var engine scan.Engine = entroClient
+	if cfg.CacheDir != "" {
+		maxAge, err := time.ParseDuration(cfg.CacheMaxAge)
+		if err != nil || maxAge < 0 {
+			logger.Error("invalid --cache-max-age, expected a duration like 168h", "value", cfg.CacheMaxAge)
+			return policy.ExitConfig
+		}
+
+		engine = &scan.Cache{
+			Engine:  entroClient,
+			Dir:     cfg.CacheDir,
+			Version: fmt.Sprintf("%s/%s generic=%t %s", cfg.APIEndpoint, entro.ScanPrefix, cfg.ScanGenerics, cfg.CacheKey),
+			MaxAge:  maxAge,
+			Logger:  logger,
+		}
+	}
+
+	ctx := context.Background()
+
+	env := ci.Detect(a.getenv)
+	format = format.Resolve(env.Provider)
+	logger.Debug("detected CI", "provider", env.Provider, "base", env.Base, "head", env.Head, "repository", env.Repository, "pull_request", env.PullRequest, "format", format)
+
+	var source scan.Source
+	if cfg.Patch != "" {
+		commits, err := a.readPatch(cfg.Patch)
+		if err != nil {
+			logger.Error("can't read patch", "path", cfg.Patch, "error", err)
+			return policy.ExitError
+		}
+		logger.Info("scanning patch", "path", cfg.Patch, "commits", len(commits))
+		source = scan.Commits(commits)
+	} else {
+		path, err := filepath.Abs(repoPath)
+		if err != nil {
+			logger.Error("can't get absolute path", "path", repoPath, "error", err)
+			return policy.ExitError
+		}
+
+		differ, err := git.NewDiffer(path, logger)
+		if err != nil {
+			logger.Error("can't create git differ", "error", err)
+			return policy.ExitError
+		}
+
+		// audit and baseline scan the whole history up to --head.
+		commitRange := git.Range{Head: cfg.Head}
+		mergeBase := false
+		if mode == modeScan {
+			if cfg.Base == "" && cfg.Head == "" {
+				commitRange = git.Range{Base: env.Base, Head: env.Head}
+				mergeBase = env.MergeBase
+			} else {
+				commitRange.Base = cfg.Base
+			}
+		}
+
+		repository := &scan.Repository{
+			Differ:    differ,
+			Range:     commitRange,
+			MergeBase: mergeBase,
+			Logger:    logger,
+		}
+		if cfg.Deepen {
+			repository.Remote = cfg.Remote
+		}
+		source = repository
+	}
+
+	scanner := &scan.Scanner{
+		Source:      source,
+		Engine:      engine,
+		Filters:     filters,
+		Policy:      scanPolicy,
+		FailOnError: cfg.FailOnError,
+		Redactor:    redactor,
+		Logger:      logger,
+	}
+	if mode != modeBaseline {
+		scanner.Reporters = a.reporters(cfg, logger, format)
+	}
+	if cfg.Batch {
+		scanner.Batch = entro.MaxBatchItems
+	}
+	if cfg.Provenance {
+		scanner.Provenance = &scan.Provenance{Repository: env.Repository, PullRequest: env.PullRequest}
+	}
+	if cfg.Verify && mode != modeBaseline {
+		scanner.Verifier = verify.New(verify.Endpoints{
+			GitHub: cfg.VerifyGitHubURL,
+			Slack:  cfg.VerifySlackURL,
+			Basic:  cfg.VerifyBasicURL,
+		}, nil)
+	}
+	if inGitHub && logFormat == logging.FormatText {
+		scanner.Group = func(name string) func() {
+			return logging.Group(stdout, true, name)
+		}
+	}
+
+	result, err := scanner.Run(ctx)
+	if errors.Is(err, git.ErrShallow) {
+		logger.Error("the checkout lacks the history of the scanned range, fetch more of it (fetch-depth: 0) or enable --deepen",
+			"error", err, "see", "https://github.com/liminal-security/scan-action#troubleshooting")
+		return policy.ExitShallow
+	}
+	if errors.Is(err, scan.ErrNoCommits) {
+		logger.Info("no commits to scan")
+		return policy.ExitOK
+	}
+	if err != nil {
+		logger.Error("scan failed", "error", err)
+		return policy.ExitError
+	}
+
+	if mode == modeBaseline {
+		return writeBaseline(cfg, logger, result)
+	}
+
+	secrets := result.Secrets
+	if len(secrets) == 0 {
+		logger.Info("no secrets found")
+		return policy.ExitOK
+	}
+
+	// Secrets only removed by the scanned commits are informational, they
+	// are still in the git history though, so remind to rotate them.
+	if err := report.WriteFindings(stdout, result, format); err != nil {
+		logger.Error("can't print findings", "error", err)
+	}
+
+	logger.Info("found secrets", "count", len(secrets), "failing", len(result.Failing()), "fail_on", scanPolicy.FailOn)
+
+	return result.ExitCode
+}
+
+func writeBaseline(cfg *config, logger *slog.Logger, result *report.Result) int {
+	path := cfg.Baseline
+	if path == "" {
+		path = defaultBaseline
+	}
+
+	write := func(w io.Writer, result *report.Result) error {
+		return findings.NewBaseline(result.Secrets).Write(w)
+	}
+	if err := scan.File(path, write).Report(context.Background(), result); err != nil {
+		logger.Error("can't write baseline", "error", err)
+		return policy.ExitError
+	}
+
+	logger.Info("wrote baseline", "path", path, "count", len(result.Secrets))
+
+	return policy.ExitOK
+}
+
+// reporters returns the configured reports, failing to write a report file
+// fails the scan, failing to publish to GitHub only warns.
+func (a *app) reporters(cfg *config, logger *slog.Logger, format report.Format) []scan.Reporter {
+	var reporters []scan.Reporter
+
+	if cfg.OutputJSON != "" {
+		reporters = append(reporters, scan.File(cfg.OutputJSON, report.WriteJSON))
+	}
+
+	if cfg.OutputJUnit != "" {
+		reporters = append(reporters, scan.File(cfg.OutputJUnit, report.WriteJUnit))
+	}
+
+	if cfg.OutputSARIF != "" {
+		reporters = append(reporters, scan.File(cfg.OutputSARIF, report.WriteSARIF))
+	}
+
+	gitlabPath := cfg.GitLabReport
+	if gitlabPath == "" && format == report.FormatGitLab {
+		gitlabPath = report.GitLabReportName
+	}
+	if gitlabPath != "" {
+		reporters = append(reporters, scan.File(gitlabPath, report.WriteGitLab))
+	}
+
+	if format == report.FormatBitbucket {
+		reporters = append(reporters,
+			scan.File(report.BitbucketReportName, report.WriteBitbucketReport),
+			scan.File(report.BitbucketAnnotationsName, report.WriteBitbucketAnnotations),
+		)
+	}
+
+	warnOnly := func(msg string, publish func(ctx context.Context, result *report.Result) error) scan.Reporter {
+		return scan.ReporterFunc(func(ctx context.Context, result *report.Result) error {
+			if err := publish(ctx, result); err != nil {
+				logger.Warn(msg, "error", err)
+			}
+
+			return nil
+		})
+	}
+
+	if summaryPath := a.getenv("GITHUB_STEP_SUMMARY"); summaryPath != "" {
+		reporters = append(reporters, warnOnly("can't write job summary", func(_ context.Context, result *report.Result) error {
+			return a.writeSummary(summaryPath, result)
+		}))
+	}
+
+	if cfg.PRComments {
+		reporters = append(reporters, warnOnly("can't post pull request review", func(ctx context.Context, result *report.Result) error {
+			return a.postReview(ctx, logger, result)
+		}))
+	}
+
+	if cfg.Checks {
+		reporters = append(reporters, warnOnly("can't create check run", a.createCheckRun))
+	}
+
+	if cfg.CodeInsights {
+		reporters = append(reporters, warnOnly("can't upload Code Insights report", a.uploadCodeInsights))
+	}
+
+	return reporters
+}
+
+// writeSummary appends the Markdown summary to the GitHub job summary file.
+func (a *app) writeSummary(path string, result *report.Result) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("can't open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var repoURL string
+	if repo := a.getenv("GITHUB_REPOSITORY"); repo != "" {
+		serverURL := a.getenv("GITHUB_SERVER_URL")
+		if serverURL == "" {
+			serverURL = "https://github.com"
+		}
+		repoURL = serverURL + "/" + repo
+	}
+
+	return report.WriteSummary(file, result, repoURL)
+}
+
+// githubContext reads the repository and event of the running workflow.
+func (a *app) githubContext() (client *github.Client, owner string, repo string, event *github.Event, err error) {
+	token := a.getenv("GITHUB_TOKEN")
+	if token == "" {
+		return nil, "", "", nil, fmt.Errorf("GITHUB_TOKEN is not set")
+	}
+
+	owner, repo, err = github.SplitRepository(a.getenv("GITHUB_REPOSITORY"))
+	if err != nil {
+		return nil, "", "", nil, err
+	}
+
+	event, err = github.ReadEvent(a.getenv("GITHUB_EVENT_PATH"))
+	if err != nil {
+		return nil, "", "", nil, err
+	}
+
+	return github.NewClient(a.getenv("GITHUB_API_URL"), token), owner, repo, event, nil
+}
+
+// postReview comments the findings on the pull request the workflow runs for.
+func (a *app) postReview(ctx context.Context, logger *slog.Logger, result *report.Result) error {
+	client, owner, repo, event, err := a.githubContext()
+	if err != nil {
+		return err
+	}
+	if event.PullRequest == nil {
+		logger.Info("not a pull request event, skipping review comments")
+
+		return nil
+	}
+
+	review := &report.Review{
+		Client:      client,
+		Owner:       owner,
+		Repo:        repo,
+		PullRequest: event.PullRequest,
+	}
+
+	return review.Report(ctx, result)
+}
+
+// createCheckRun reports the result as a check run of the scanned head.
+func (a *app) createCheckRun(ctx context.Context, result *report.Result) error {
+	client, owner, repo, event, err := a.githubContext()
+	if err != nil {
+		return err
+	}
+
+	headSHA := a.getenv("GITHUB_SHA")
+	if event.PullRequest != nil {
+		headSHA = event.PullRequest.Head.SHA
+	}
+
+	checks := &report.Checks{
+		Client:  client,
+		Owner:   owner,
+		Repo:    repo,
+		HeadSHA: headSHA,
+		Name:    "Entro secrets scan",
+	}
+
+	return checks.Report(ctx, result)
+}
+
+// uploadCodeInsights uploads the Code Insights report of the commit built
+// by Bitbucket Pipelines.
+func (a *app) uploadCodeInsights(ctx context.Context, result *report.Result) error {
+	repository, commit := a.getenv("BITBUCKET_REPO_FULL_NAME"), a.getenv("BITBUCKET_COMMIT")
+	if repository == "" || commit == "" {
+		return fmt.Errorf("BITBUCKET_REPO_FULL_NAME and BITBUCKET_COMMIT are not set")
+	}
+
+	client, err := bitbucket.NewClient(bitbucket.DefaultAPIURL, bitbucket.PipelinesProxy)
+	if err != nil {
+		return err
+	}
+
+	insights := &report.CodeInsights{
+		Client:     client,
+		Repository: repository,
+		Commit:     commit,
+	}
+
+	return insights.Report(ctx, result)
+}
+
+// readPatch parses the unified diff or mbox series at path, - is stdin.
+func (a *app) readPatch(path string) ([]git.Commit, error) {
+	if path == "-" {
+		return git.ParsePatch(a.stdin)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open patch: %w", err)
+	}
+	defer file.Close()
+
+	return git.ParsePatch(file)
+}
+
+// tlsVersions are the accepted --min-tls-version values.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// clientOptions returns the user agent, proxy and TLS options of the API
+// client. The proxy is read from HTTPS_PROXY and NO_PROXY like curl does.
+func (a *app) clientOptions(cfg *config) ([]entro.Option, error) {
+	minVersion, ok := tlsVersions[cfg.MinTLSVersion]
+	if !ok {
+		return nil, fmt.Errorf("invalid --min-tls-version %q, expected 1.2 or 1.3", cfg.MinTLSVersion)
+	}
+
+	opts := []entro.Option{
+		entro.WithUserAgent(entro.DefaultUserAgent + "/" + version()),
+		entro.WithProxy(a.lookupEnv("HTTPS_PROXY", "https_proxy"), a.lookupEnv("NO_PROXY", "no_proxy")),
+		entro.WithMinTLSVersion(minVersion),
+	}
+	if cfg.CAFiles != "" {
+		var paths []string
+		for _, path := range strings.Split(cfg.CAFiles, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				paths = append(paths, path)
+			}
+		}
+		opts = append(opts, entro.WithCAFiles(paths...))
+	}
+	if cfg.ClientCert != "" {
+		keyFile := cfg.ClientKey
+		if keyFile == "" {
+			keyFile = cfg.ClientCert
+		}
+		opts = append(opts, entro.WithClientCert(cfg.ClientCert, keyFile))
+	} else if cfg.ClientKey != "" {
+		return nil, errors.New("--client-key needs --client-cert")
+	}
+
+	return opts, nil
+}
+
+// lookupEnv returns the first set environment variable of keys.
+func (a *app) lookupEnv(keys ...string) string {
+	for _, key := range keys {
+		if value := a.getenv(key); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,160 @@
+package entro
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// BatchPrefix scans several payloads in one request. Every item is scanned
+// like a ScanPrefix request and, when it has provenance, recorded like a
+// ProvenancePrefix one.
+const BatchPrefix = ScanPrefix + "/batch"
+
+const (
+	// MaxBatchSize is the encoded size of the items packed into one batch,
+	// below the 1MB body limit of the API.
+	MaxBatchSize = 900 << 10
+	// MaxBatchItems is the number of items packed into one batch.
+	MaxBatchItems = 100
+)
+
+// BatchItem is a scan request identified within its batch.
+type BatchItem struct {
+	ID string `json:"id"`
+	ScanReq
+}
+
+type BatchReq struct {
+	Items []BatchItem `json:"items"`
+}
+
+// BatchItemResp is the response to the item with ID, Error is set when the
+// item couldn't be scanned.
+type BatchItemResp struct {
+	ID string `json:"id"`
+	ScanResp
+	Error string `json:"error,omitempty"`
+}
+
+type BatchResp struct {
+	RequestID string          `json:"requestId"`
+	Results   []BatchItemResp `json:"results"`
+}
+
+// BatchResult is the response or the error of one request of ScanBatch.
+type BatchResult struct {
+	Resp *ScanResp
+	Err  error
+}
+
+// ScanBatch scans the requests, packing them into BatchPrefix requests of
+// up to MaxBatchSize and MaxBatchItems. Requests too large to share a
+// batch are sent alone with Scan, as are all of them once the API answered
+// it doesn't support batches. The results are in the order of the
+// requests.
+func (c *Client) ScanBatch(ctx context.Context, scanReqs []*ScanReq) []BatchResult {
+	results := make([]BatchResult, len(scanReqs))
+
+	var (
+		indexes []int
+		items   []json.RawMessage
+		size    int
+	)
+	flush := func() {
+		if len(indexes) > 0 {
+			c.scanBatch(ctx, scanReqs, indexes, items, results)
+		}
+		indexes, items, size = nil, nil, 0
+	}
+
+	for i, scanReq := range scanReqs {
+		item, err := json.Marshal(BatchItem{ID: strconv.Itoa(i), ScanReq: *scanReq})
+		if err != nil {
+			results[i].Err = fmt.Errorf("can't encode request body: %w", err)
+			continue
+		}
+		if len(item) > MaxBatchSize {
+			results[i].Resp, results[i].Err = c.Scan(ctx, scanReq)
+			continue
+		}
+
+		// Items are separated by a comma.
+		if size+len(item)+1 > MaxBatchSize || len(indexes) == MaxBatchItems {
+			flush()
+		}
+		indexes = append(indexes, i)
+		items = append(items, item)
+		size += len(item) + 1
+	}
+	flush()
+
+	return results
+}
+
+// scanBatch scans the encoded items of the requests at indexes in one
+// request and sets their results.
+func (c *Client) scanBatch(ctx context.Context, scanReqs []*ScanReq, indexes []int, items []json.RawMessage, results []BatchResult) {
+	scanEach := func() {
+		for _, i := range indexes {
+			results[i].Resp, results[i].Err = c.Scan(ctx, scanReqs[i])
+		}
+	}
+	if len(indexes) == 1 || c.unbatched.Load() {
+		scanEach()
+		return
+	}
+
+	body := new(bytes.Buffer)
+	err := json.NewEncoder(body).Encode(struct {
+		Items []json.RawMessage `json:"items"`
+	}{items})
+	if err != nil {
+		for _, i := range indexes {
+			results[i].Err = fmt.Errorf("can't encode request body: %w", err)
+		}
+		return
+	}
+
+	var resp BatchResp
+	err = c.post(ctx, BatchPrefix, body, &resp)
+	switch {
+	case errors.Is(err, &APIError{Code: http.StatusNotFound}), errors.Is(err, &APIError{Code: http.StatusMethodNotAllowed}):
+		c.logger.Warn("the API doesn't support batches, scanning commits one by one", "error", err)
+		c.unbatched.Store(true)
+		scanEach()
+		return
+	case errors.Is(err, &APIError{Code: http.StatusRequestEntityTooLarge}):
+		c.logger.Warn("batch too large, scanning its items one by one", "items", len(indexes), "error", err)
+		scanEach()
+		return
+	case err != nil:
+		for _, i := range indexes {
+			results[i].Err = err
+		}
+		return
+	}
+
+	c.logger.Debug("scanned batch", "items", len(indexes), "request_id", resp.RequestID)
+
+	byID := make(map[string]BatchItemResp, len(resp.Results))
+	for _, item := range resp.Results {
+		byID[item.ID] = item
+	}
+	for _, i := range indexes {
+		item, ok := byID[strconv.Itoa(i)]
+		switch {
+		case !ok:
+			results[i].Err = fmt.Errorf("no result for batch item %d", i)
+		case item.Error != "":
+			results[i].Err = fmt.Errorf("batch item %d failed: %s", i, item.Error)
+		default:
+			scanResp := item.ScanResp
+			results[i].Resp = &scanResp
+		}
+	}
+}
@@ -1,13 +1,17 @@
 package entro
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
+
+	"github.com/liminal-security/scan-action/logging"
 )
 
 type APIError struct {
@@ -34,57 +38,78 @@ func (e *APIError) Is(target error) bool {
 }
 
 type transport struct {
-	token string
+	token     string
+	userAgent string
+	base      http.RoundTripper
+	logger    *slog.Logger
 }
 
 type Client struct {
 	endpoint string
-	token    string
+	generics bool
 
 	httpClient *retryablehttp.Client
+	logger     *slog.Logger
+
+	// unbatched is set once the API answered it doesn't support batches.
+	unbatched atomic.Bool
 }
 
+// RoundTrip sets the headers of every request, retries included.
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.Header.Set("accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", t.token)
+	req.Header.Set("User-Agent", t.userAgent)
 
-	// Debug logging if enabled
-	if os.Getenv("ENTRO_DEBUG") == "true" {
-		fmt.Printf("Debug: Sending request to %s\n", req.URL)
-		fmt.Printf("Debug: Authorization header length: %d chars\n", len(t.token))
-		fmt.Printf("Debug: Authorization header starts with: %s...\n", truncate(t.token, 10))
-	}
+	// Never log the token itself, its length is enough to spot an empty
+	// or truncated secret.
+	t.logger.Debug("sending request",
+		"url", req.URL.String(),
+		"authorization_length", len(t.token),
+	)
 
-	return http.DefaultTransport.RoundTrip(req)
+	return t.base.RoundTrip(req)
 }
 
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+// NewClient creates a scan API client. By default it retries failed
+// requests twice, waiting 1 to 5 seconds, times requests out after 30
+// seconds and discards its logs.
+func NewClient(endpoint string, token string, opts ...Option) (*Client, error) {
+	o := &options{
+		minVersion: tls.VersionTLS12,
+		retries:    2,
+		waitMin:    1 * time.Second,
+		waitMax:    5 * time.Second,
+		timeout:    30 * time.Second,
+		userAgent:  DefaultUserAgent,
 	}
-	return s[:maxLen]
-}
-
-// Helper to truncate strings - exported for use in other files
-func Truncate(s string, maxLen int) string {
-	return truncate(s, maxLen)
-}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	logger := logging.OrDiscard(o.logger)
 
-func NewClient(endpoint string, token string) (client *Client) {
 	retryClient := retryablehttp.NewClient()
 	retryClient.Logger = nil
-	retryClient.RetryMax = 2
-	retryClient.RetryWaitMin = 1 * time.Second
-	retryClient.RetryWaitMax = 5 * time.Second
-	retryClient.HTTPClient.Timeout = 30 * time.Second // Increased from 1s to 30s
-	retryClient.HTTPClient.Transport = &transport{token: token}
+	retryClient.RetryMax = o.retries
+	retryClient.RetryWaitMin = o.waitMin
+	retryClient.RetryWaitMax = o.waitMax
+	retryClient.HTTPClient.Timeout = o.timeout
+	retryClient.HTTPClient.Transport = &transport{
+		token:     token,
+		userAgent: o.userAgent,
+		base:      o.roundTripper(),
+		logger:    logger,
+	}
 
 	retryClient.CheckRetry = retryablehttp.ErrorPropagatedRetryPolicy
 
 	return &Client{
 		endpoint:   endpoint,
-		token:      token,
+		generics:   o.generics,
 		httpClient: retryClient,
-	}
+		logger:     logger,
+	}, nil
 }
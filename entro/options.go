@@ -0,0 +1,208 @@
+package entro
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// DefaultUserAgent is the User-Agent of the requests unless WithUserAgent
+// sets another.
+const DefaultUserAgent = "scan-action"
+
+// Option configures a Client, see NewClient.
+type Option func(*options) error
+
+type options struct {
+	proxy        func(*http.Request) (*url.URL, error)
+	rootCAs      *x509.CertPool
+	certificates []tls.Certificate
+	minVersion   uint16
+
+	retries          int
+	waitMin, waitMax time.Duration
+	timeout          time.Duration
+	userAgent        string
+	base             http.RoundTripper
+	logger           *slog.Logger
+	generics         bool
+}
+
+// WithGenerics makes the API report generic secrets too, like high entropy
+// strings.
+func WithGenerics(enabled bool) Option {
+	return func(o *options) error {
+		o.generics = enabled
+
+		return nil
+	}
+}
+
+// WithRetries retries failed requests up to n times, 0 disables retries.
+func WithRetries(n int) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("invalid retries %d", n)
+		}
+		o.retries = n
+
+		return nil
+	}
+}
+
+// WithBackoff waits exponentially longer between retries, from minWait up
+// to maxWait. Retry-After headers of 429 and 503 responses are honored.
+func WithBackoff(minWait, maxWait time.Duration) Option {
+	return func(o *options) error {
+		if minWait < 0 || maxWait < minWait {
+			return fmt.Errorf("invalid backoff from %s to %s", minWait, maxWait)
+		}
+		o.waitMin, o.waitMax = minWait, maxWait
+
+		return nil
+	}
+}
+
+// WithTimeout times every request attempt out after d, 0 disables the
+// timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		if d < 0 {
+			return fmt.Errorf("invalid timeout %s", d)
+		}
+		o.timeout = d
+
+		return nil
+	}
+}
+
+// WithUserAgent sends userAgent as the User-Agent of the requests.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) error {
+		if userAgent == "" {
+			return errors.New("empty user agent")
+		}
+		o.userAgent = userAgent
+
+		return nil
+	}
+}
+
+// WithRoundTripper sends the requests with rt, e.g. a test double or an
+// instrumented transport. The proxy and TLS options don't apply to it.
+func WithRoundTripper(rt http.RoundTripper) Option {
+	return func(o *options) error {
+		o.base = rt
+
+		return nil
+	}
+}
+
+// WithLogger logs the requests to logger at debug level, the token is
+// never logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) error {
+		o.logger = logger
+
+		return nil
+	}
+}
+
+// WithProxy sends the requests through httpsProxy, except those to the
+// hosts of the comma separated noProxy list, like the HTTPS_PROXY and
+// NO_PROXY environment variables. An empty httpsProxy connects directly.
+// Without it the proxy is read from the process environment.
+func WithProxy(httpsProxy, noProxy string) Option {
+	return func(o *options) error {
+		if httpsProxy != "" {
+			if _, err := url.Parse(httpsProxy); err != nil {
+				return fmt.Errorf("can't parse proxy URL: %w", err)
+			}
+		}
+
+		proxyFunc := (&httpproxy.Config{HTTPSProxy: httpsProxy, NoProxy: noProxy}).ProxyFunc()
+		o.proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+
+		return nil
+	}
+}
+
+// WithCAFiles trusts the certificates of the PEM files on top of the
+// system ones, e.g. the CA of a TLS inspecting proxy or of an on-prem
+// deployment.
+func WithCAFiles(paths ...string) Option {
+	return func(o *options) error {
+		if o.rootCAs == nil {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			o.rootCAs = pool
+		}
+
+		for _, path := range paths {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("can't read CA file: %w", err)
+			}
+			if !o.rootCAs.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("no certificates in CA file %s", path)
+			}
+		}
+
+		return nil
+	}
+}
+
+// WithClientCert authenticates the client with the PEM certificate and key
+// files, for endpoints requiring mutual TLS.
+func WithClientCert(certFile, keyFile string) Option {
+	return func(o *options) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("can't load client certificate: %w", err)
+		}
+		o.certificates = append(o.certificates, cert)
+
+		return nil
+	}
+}
+
+// WithMinTLSVersion refuses servers not supporting version, one of the
+// tls.VersionTLS* constants. It defaults to TLS 1.2.
+func WithMinTLSVersion(version uint16) Option {
+	return func(o *options) error {
+		o.minVersion = version
+
+		return nil
+	}
+}
+
+// roundTripper returns the transport the options describe.
+func (o *options) roundTripper() http.RoundTripper {
+	if o.base != nil {
+		return o.base
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if o.proxy != nil {
+		base.Proxy = o.proxy
+	}
+	base.TLSClientConfig = &tls.Config{
+		RootCAs:      o.rootCAs,
+		Certificates: o.certificates,
+		MinVersion:   o.minVersion,
+	}
+
+	return base
+}
@@ -0,0 +1,301 @@
+package entro
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientTLS(t *testing.T) {
+	token := "ent_test-token"
+	dir := t.TempDir()
+	certFile, keyFile, clientCAs := writeClientCert(t, dir)
+
+	svr := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveFile(t, w, r, "testdata/zero_results.json", 0, token)
+	}))
+	svr.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+		MaxVersion: tls.VersionTLS12,
+	}
+	// The failing handshakes are expected.
+	svr.Config.ErrorLog = log.New(io.Discard, "", 0)
+	svr.StartTLS()
+	defer svr.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", svr.Certificate().Raw)
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "untrusted server", opts: []Option{WithClientCert(certFile, keyFile)}, wantErr: true},
+		{name: "no client certificate", opts: []Option{WithCAFiles(caFile)}, wantErr: true},
+		{name: "mutual TLS", opts: []Option{WithCAFiles(caFile), WithClientCert(certFile, keyFile)}},
+		{name: "TLS 1.3 only", opts: []Option{WithCAFiles(caFile), WithClientCert(certFile, keyFile), WithMinTLSVersion(tls.VersionTLS13)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testClient(t, svr.URL, token, append(tt.opts, WithRetries(0))...)
+
+			_, err := c.Scan(context.Background(), &ScanReq{Data: "test"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Scan() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientProxy(t *testing.T) {
+	token := "ent_test-token"
+
+	svr := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveFile(t, w, r, "testdata/zero_results.json", 0, token)
+	}))
+	defer svr.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", svr.Certificate().Raw)
+
+	// The proxy tunnels every CONNECT to svr, example.com is one of the
+	// names of its certificate.
+	var connects []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		connects = append(connects, r.Host)
+
+		upstream, err := net.Dial("tcp", svr.Listener.Addr().String())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("can't hijack: %s", err)
+			return
+		}
+		defer conn.Close()
+
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+	}))
+	defer proxy.Close()
+
+	_, port, _ := net.SplitHostPort(svr.Listener.Addr().String())
+	endpoint := "https://example.com:" + port
+
+	c := testClient(t, endpoint, token, WithCAFiles(caFile), WithProxy(proxy.URL, "internal.example.com"), WithRetries(0))
+
+	if _, err := c.Scan(context.Background(), &ScanReq{Data: "test"}); err != nil {
+		t.Fatalf("Scan() error = %s", err)
+	}
+	if len(connects) != 1 || connects[0] != "example.com:"+port {
+		t.Errorf("proxy CONNECTs = %v, want example.com:%s", connects, port)
+	}
+
+	o := &options{}
+	if err := WithProxy(proxy.URL, "internal.example.com")(o); err != nil {
+		t.Fatalf("WithProxy() error = %s", err)
+	}
+	proxyURL, err := o.proxy(httptest.NewRequest(http.MethodPost, "https://internal.example.com/v2/scan", nil))
+	if err != nil || proxyURL != nil {
+		t.Errorf("proxy for a NO_PROXY host = %v, %v, want none", proxyURL, err)
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	token := "ent_test-token"
+
+	var attempts int
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		serveFile(t, w, r, "testdata/zero_results.json", 0, token)
+	}))
+	defer svr.Close()
+
+	c := testClient(t, svr.URL, token, WithRetries(1), WithBackoff(time.Millisecond, time.Millisecond))
+	if _, err := c.Scan(context.Background(), &ScanReq{Data: "test"}); err == nil {
+		t.Error("Scan() error = nil, want the second 502")
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+
+	if _, err := c.Scan(context.Background(), &ScanReq{Data: "test"}); err != nil {
+		t.Errorf("Scan() error = %s", err)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer svr.Close()
+	defer close(release)
+
+	c := testClient(t, svr.URL, "ent_test-token", WithRetries(0), WithTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	if _, err := c.Scan(context.Background(), &ScanReq{Data: "test"}); err == nil {
+		t.Error("Scan() error = nil, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Scan() took %s, want it to time out", elapsed)
+	}
+}
+
+// roundTripperFunc adapts a func to an http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestClientRoundTripper(t *testing.T) {
+	var got *http.Request
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"requestId":"r1","totalCount":0,"results":[]}`)),
+			Request:    req,
+		}, nil
+	})
+
+	c := testClient(t, "https://api.entro.security", "ent_test-token", WithRoundTripper(rt), WithUserAgent("scan-action/1.2.3"))
+	resp, err := c.Scan(context.Background(), &ScanReq{Data: "test"})
+	if err != nil {
+		t.Fatalf("Scan() error = %s", err)
+	}
+	if resp.RequestID != "r1" {
+		t.Errorf("RequestID = %q, want r1", resp.RequestID)
+	}
+
+	if got.URL.String() != "https://api.entro.security/"+ScanPrefix {
+		t.Errorf("URL = %s", got.URL)
+	}
+	if auth := got.Header.Values("Authorization"); len(auth) != 1 || auth[0] != "ent_test-token" {
+		t.Errorf("Authorization = %q, want the token once", auth)
+	}
+	if ua := got.Header.Get("User-Agent"); ua != "scan-action/1.2.3" {
+		t.Errorf("User-Agent = %q", ua)
+	}
+
+	c = testClient(t, "https://api.entro.security", "ent_test-token", WithRoundTripper(rt), WithGenerics(true))
+	if _, err := c.Scan(context.Background(), &ScanReq{Data: "test"}); err != nil {
+		t.Fatalf("Scan() error = %s", err)
+	}
+	if generic := got.URL.Query().Get("generic"); generic != "true" {
+		t.Errorf("generic = %q, want true", generic)
+	}
+}
+
+func TestNewClientOptionErrors(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{name: "missing CA file", opt: WithCAFiles(filepath.Join(dir, "missing.pem"))},
+		{name: "no certificates", opt: WithCAFiles(empty)},
+		{name: "missing client certificate", opt: WithClientCert(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))},
+		{name: "invalid proxy", opt: WithProxy("http://proxy:port", "")},
+		{name: "negative retries", opt: WithRetries(-1)},
+		{name: "inverted backoff", opt: WithBackoff(2*time.Second, time.Second)},
+		{name: "negative timeout", opt: WithTimeout(-time.Second)},
+		{name: "empty user agent", opt: WithUserAgent("")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClient("https://api.entro.security", "ent_test-token", tt.opt); err == nil {
+				t.Error("NewClient() error = nil, want one")
+			}
+		})
+	}
+}
+
+// writeClientCert writes a self-signed client certificate and its key to
+// dir, the returned pool trusts it.
+func writeClientCert(t *testing.T, dir string) (certFile, keyFile string, pool *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "scan-action"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "PRIVATE KEY", keyDER)
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return certFile, keyFile, pool
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -6,16 +6,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
-	"os"
 
 	"github.com/hashicorp/go-retryablehttp"
 )
 
 const ScanPrefix = "v2/scan"
 
+// ProvenancePrefix scans like ScanPrefix and records the findings in the
+// Entro inventory along with the provenance of the request. It is
+// versioned with ScanPrefix.
+const ProvenancePrefix = ScanPrefix + "/provenance"
+
 type ScanResult struct {
 	Origin string `json:"origin"`
 	Value  string `json:"value"`
@@ -29,98 +32,103 @@ type ScanResp struct {
 
 type ScanReq struct {
 	Data string `json:"data"`
+	// Provenance, when set, sends the request to ProvenancePrefix.
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// Provenance tells the platform where the scanned data comes from.
+type Provenance struct {
+	// Repository is the web URL of the repository.
+	Repository string `json:"repository,omitempty"`
+	Commit     string `json:"commit"`
+	Author     string `json:"author,omitempty"`
+	// Files are the files whose changes make up the data.
+	Files       []string `json:"files,omitempty"`
+	PullRequest int      `json:"pullRequest,omitempty"`
 }
 
 func (c *Client) Scan(ctx context.Context, scanReq *ScanReq) (*ScanResp, error) {
-	reqURL, err := url.JoinPath(c.endpoint, ScanPrefix)
+	prefix := ScanPrefix
+	if scanReq.Provenance != nil {
+		prefix = ProvenancePrefix
+	}
+
+	body := new(bytes.Buffer)
+	err := json.NewEncoder(body).Encode(scanReq)
+	if err != nil {
+		return nil, fmt.Errorf("can't encode request body: %w", err)
+	}
+
+	var result ScanResp
+	if err := c.post(ctx, prefix, body, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// post sends body to the API path prefix and decodes the response into v.
+func (c *Client) post(ctx context.Context, prefix string, body io.Reader, v any) error {
+	reqURL, err := url.JoinPath(c.endpoint, prefix)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("can't build scan URL: %w", err)
 	}
 
 	// Add generic=true query parameter if enabled
-	if os.Getenv("ENTRO_SCAN_GENERICS") == "true" {
+	if c.generics {
 		parsedURL, err := url.Parse(reqURL)
 		if err != nil {
-			return nil, fmt.Errorf("can't parse URL: %w", err)
+			return fmt.Errorf("can't parse URL: %w", err)
 		}
 		query := parsedURL.Query()
 		query.Set("generic", "true")
 		parsedURL.RawQuery = query.Encode()
 		reqURL = parsedURL.String()
 
-		if os.Getenv("ENTRO_DEBUG") == "true" {
-			fmt.Println("Debug: Generic scanning enabled")
-		}
-	}
-
-	body := new(bytes.Buffer)
-	err = json.NewEncoder(body).Encode(scanReq)
-	if err != nil {
-		return nil, fmt.Errorf("can't encode request body: %w", err)
+		c.logger.Debug("generic scanning enabled")
 	}
 
 	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, reqURL, body)
 	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Set headers explicitly on the retryablehttp request
-	req.Header.Set("accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", c.token)
-
-	// Debug: Verify headers are set
-	if os.Getenv("ENTRO_DEBUG") == "true" {
-		fmt.Printf("Debug: Headers being sent:\n")
-		fmt.Printf("  Content-Type: %s\n", req.Header.Get("Content-Type"))
-		authHeader := req.Header.Get("Authorization")
-		if len(authHeader) > 10 {
-			fmt.Printf("  Authorization: %s... (%d chars)\n", authHeader[:10], len(authHeader))
-		} else {
-			fmt.Printf("  Authorization: %s (%d chars)\n", authHeader, len(authHeader))
-		}
+		return fmt.Errorf("can't create scan request: %w", err)
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("scan request failed: %w", err)
+		return fmt.Errorf("scan request failed: %w", err)
 	}
 
 	defer resp.Body.Close()
 
-	// Debug: Log response status
-	if os.Getenv("ENTRO_DEBUG") == "true" {
-		fmt.Printf("Debug: Response status: %d %s\n", resp.StatusCode, resp.Status)
-	}
+	c.logger.Debug("scan response", "status", resp.Status)
 
 	if resp.StatusCode != http.StatusOK {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("can't read response body: %w", err)
+			return fmt.Errorf("can't read response body: %w", err)
 		}
 
 		// Log additional details for common error codes
 		switch resp.StatusCode {
 		case http.StatusForbidden:
-			fmt.Fprintf(os.Stderr, "API returned 403 Forbidden - check your API token is valid and has scan permissions\n")
+			c.logger.Error("API returned 403 Forbidden - check your API token is valid and has scan permissions")
 		case http.StatusUnauthorized:
-			fmt.Fprintf(os.Stderr, "API returned 401 Unauthorized - check your API token\n")
+			c.logger.Error("API returned 401 Unauthorized - check your API token")
 		case http.StatusTooManyRequests:
-			fmt.Fprintf(os.Stderr, "API returned 429 Too Many Requests - you're being rate limited\n")
+			c.logger.Error("API returned 429 Too Many Requests - you're being rate limited")
 		}
 
-		return nil, &APIError{
+		return &APIError{
 			Code:    resp.StatusCode,
 			Message: string(body),
 		}
 	}
 
 	decoder := json.NewDecoder(resp.Body)
-	var result ScanResp
-	err = decoder.Decode(&result)
+	err = decoder.Decode(v)
 	if err != nil {
-		return nil, fmt.Errorf("can't decode response: %w", err)
+		return fmt.Errorf("can't decode response: %w", err)
 	}
 
-	return &result, nil
+	return nil
 }
@@ -1,12 +1,16 @@
 package entro
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -72,7 +76,7 @@ func TestClientScan(t *testing.T) {
 			}))
 			defer svr.Close()
 
-			c := NewClient(svr.URL, token)
+			c := testClient(t, svr.URL, token)
 
 			got, err := c.Scan(context.Background(), tt.req)
 			if !errors.Is(err, tt.wantErr) {
@@ -88,6 +92,84 @@ func TestClientScan(t *testing.T) {
 	}
 }
 
+func TestClientScanDebugLogHidesToken(t *testing.T) {
+	token := "ent_test-token"
+
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveFile(t, w, r, "testdata/one_result.json", 0, token)
+	}))
+	defer svr.Close()
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	_, err := testClient(t, svr.URL, token, WithLogger(logger)).Scan(context.Background(), &ScanReq{Data: "test"})
+	if err != nil {
+		t.Fatalf("Scan() error = %s", err)
+	}
+
+	if !strings.Contains(out.String(), "authorization_length=14") {
+		t.Errorf("expected the token length in the debug log, got:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "test-tok") {
+		t.Errorf("debug log contains the token:\n%s", out.String())
+	}
+}
+
+func TestClientScanProvenance(t *testing.T) {
+	token := "ent_test-token"
+
+	var paths []string
+	var provenance []*Provenance
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ScanReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("can't decode request: %s", err)
+		}
+		paths = append(paths, r.URL.Path)
+		provenance = append(provenance, req.Provenance)
+
+		serveFile(t, w, r, "testdata/zero_results.json", 0, token)
+	}))
+	defer svr.Close()
+
+	c := testClient(t, svr.URL, token)
+
+	want := &Provenance{
+		Repository:  "https://github.com/liminal-security/scan-action",
+		Commit:      "539533aab24270f6201fcdd5aa25f6c16662ee58",
+		Author:      "Jane Doe <jane@example.com>",
+		Files:       []string{"notes.md"},
+		PullRequest: 3,
+	}
+	_, err := c.Scan(context.Background(), &ScanReq{Data: "test", Provenance: want})
+	if err != nil {
+		t.Fatalf("Scan() error = %s", err)
+	}
+	_, err = c.Scan(context.Background(), &ScanReq{Data: "test"})
+	if err != nil {
+		t.Fatalf("Scan() error = %s", err)
+	}
+
+	if diff := cmp.Diff([]string{"/" + ProvenancePrefix, "/" + ScanPrefix}, paths); diff != "" {
+		t.Errorf("paths mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]*Provenance{want, nil}, provenance); diff != "" {
+		t.Errorf("provenance mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testClient(t *testing.T, endpoint, token string, opts ...Option) *Client {
+	t.Helper()
+
+	c, err := NewClient(endpoint, token, opts...)
+	if err != nil {
+		t.Fatalf("NewClient() error = %s", err)
+	}
+
+	return c
+}
+
 func testHeaders(t *testing.T, r *http.Request, token string) {
 	auth := r.Header.Get("Authorization")
 	if auth != token {
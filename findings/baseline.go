@@ -0,0 +1,85 @@
+package findings
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// BaselineVersion is the version of the baseline file format, version 2
+// keys hash the masked value of the secrets.
+const BaselineVersion = 2
+
+// BaselineEntry is an accepted secret, Value is masked.
+type BaselineEntry struct {
+	Key    string `json:"key"`
+	Origin string `json:"origin"`
+	File   string `json:"file"`
+	Value  string `json:"value"`
+}
+
+// Baseline lists known secrets to leave out of later scans, e.g. the ones
+// already rotated when adopting the scan on an existing repository.
+type Baseline struct {
+	Version int             `json:"version"`
+	Secrets []BaselineEntry `json:"secrets"`
+}
+
+// NewBaseline accepts secrets.
+func NewBaseline(secrets []*Secret) *Baseline {
+	b := &Baseline{Version: BaselineVersion, Secrets: []BaselineEntry{}}
+	for _, s := range secrets {
+		b.Secrets = append(b.Secrets, BaselineEntry{
+			Key:    s.Key(),
+			Origin: s.Origin,
+			File:   s.File,
+			Value:  s.Value,
+		})
+	}
+
+	sort.Slice(b.Secrets, func(i, j int) bool {
+		return b.Secrets[i].Key < b.Secrets[j].Key
+	})
+
+	return b
+}
+
+// ReadBaseline reads a baseline file.
+func ReadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read baseline: %w", err)
+	}
+
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("can't parse baseline %s: %w", path, err)
+	}
+	if b.Version != BaselineVersion {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s, write it again with scan-action baseline", b.Version, path)
+	}
+
+	return &b, nil
+}
+
+// Write writes the baseline as indented JSON.
+func (b *Baseline) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(b)
+}
+
+// Contains tells whether the finding is accepted by the baseline.
+func (b *Baseline) Contains(f Finding) bool {
+	key := f.Key()
+	for _, s := range b.Secrets {
+		if s.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package findings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/liminal-security/scan-action/git"
+)
+
+// Finding is a single secret reported by the scan API, mapped back to the
+// file and commit it was found in.
+type Finding struct {
+	Origin string
+	Value  string
+	File   string
+	Line   int
+	Commit string
+	Side   git.Side
+	// Verification is empty unless the secret was verified.
+	Verification Verification
+}
+
+// Verification tells whether a secret is live, as checked against the
+// service it belongs to.
+type Verification string
+
+const (
+	// VerificationVerified means the service accepted the secret.
+	VerificationVerified Verification = "verified"
+	// VerificationInvalid means the service rejected the secret.
+	VerificationInvalid Verification = "invalid"
+	// VerificationUnknown means the secret couldn't be checked.
+	VerificationUnknown Verification = "unknown"
+)
+
+// Fingerprint identifies the secret regardless of where it was found. It
+// hashes the masked value only, the baseline commits it to the repository
+// and a hash of the raw value would let short secrets be brute-forced.
+func (f Finding) Fingerprint() string {
+	sum := sha256.Sum256([]byte(f.Origin + "\x00" + Mask(f.Value)))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// Mask masks value the way the scan API does, keeping up to four leading
+// and trailing characters, never more than a quarter of it each.
+func Mask(value string) string {
+	runes := []rune(value)
+	n := min(4, len(runes)/4)
+
+	return string(runes[:n]) + strings.Repeat("*", len(runes)-2*n) + string(runes[len(runes)-n:])
+}
+
+// Key identifies the secret within a file, findings sharing it are
+// duplicates of each other.
+func (f Finding) Key() string {
+	return f.Fingerprint() + ":" + f.File
+}
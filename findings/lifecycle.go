@@ -0,0 +1,123 @@
+package findings
+
+import "github.com/liminal-security/scan-action/git"
+
+// Occurrence is one appearance of a secret in a commit.
+type Occurrence struct {
+	Commit string   `json:"commit"`
+	Line   int      `json:"line,omitempty"`
+	Side   git.Side `json:"side"`
+}
+
+// Secret is a consolidated view of one secret in one file across the
+// scanned commits. Repeated findings of the same secret are folded into
+// its occurrences.
+type Secret struct {
+	Fingerprint string `json:"fingerprint"`
+	Origin      string `json:"origin"`
+	Value       string `json:"value"`
+	File        string `json:"file"`
+	// IntroducedIn is the first commit adding the secret, empty if the
+	// secret predates the scanned commits.
+	IntroducedIn string `json:"introducedIn,omitempty"`
+	// RemovedIn is the commit removing the secret, empty if it is still
+	// present at HEAD.
+	RemovedIn     string       `json:"removedIn,omitempty"`
+	PresentAtHead bool         `json:"presentAtHead"`
+	Occurrences   []Occurrence `json:"occurrences"`
+	// Severity is assigned by the policy once the secret is tracked.
+	Severity Severity `json:"severity"`
+	// Verification is empty unless the secret was verified.
+	Verification Verification `json:"verification,omitempty"`
+	// Failing is set by the policy when the secret fails the scan.
+	Failing bool `json:"failing,omitempty"`
+}
+
+// Blocking reports whether the secret was added by the scanned commits.
+// Secrets only removed by them are already in the history of the base.
+func (s *Secret) Blocking() bool {
+	return s.IntroducedIn != "" || s.PresentAtHead
+}
+
+// Key identifies the secret within its file, see Finding.Key.
+func (s *Secret) Key() string {
+	return s.Fingerprint + ":" + s.File
+}
+
+// Last is the most recent occurrence of the secret.
+func (s *Secret) Last() Occurrence {
+	if len(s.Occurrences) == 0 {
+		return Occurrence{}
+	}
+
+	return s.Occurrences[len(s.Occurrences)-1]
+}
+
+// Track de-duplicates findings by origin, value fingerprint and file and
+// follows each secret across commits. Commits are expected newest first,
+// the way git.Differ returns them. Secrets are returned in order of first
+// appearance.
+func Track(findings []Finding, commits []string) []*Secret {
+	byCommit := map[string][]Finding{}
+	for _, f := range findings {
+		byCommit[f.Commit] = append(byCommit[f.Commit], f)
+	}
+
+	var secrets []*Secret
+	index := map[string]*Secret{}
+
+	for i := len(commits) - 1; i >= 0; i-- {
+		commit := commits[i]
+
+		added := map[string]int{}
+		removed := map[string]int{}
+
+		for _, f := range byCommit[commit] {
+			key := f.Key()
+
+			s, ok := index[key]
+			if !ok {
+				s = &Secret{
+					Fingerprint:  f.Fingerprint(),
+					Origin:       f.Origin,
+					Value:        f.Value,
+					File:         f.File,
+					Verification: f.Verification,
+				}
+				index[key] = s
+				secrets = append(secrets, s)
+			}
+			s.Occurrences = append(s.Occurrences, Occurrence{
+				Commit: f.Commit,
+				Line:   f.Line,
+				Side:   f.Side,
+			})
+
+			if f.Side == git.SideRemoved {
+				removed[key]++
+			} else {
+				added[key]++
+			}
+		}
+
+		for key, s := range index {
+			a, r := added[key], removed[key]
+			switch {
+			case a > r:
+				if s.IntroducedIn == "" {
+					s.IntroducedIn = commit
+				}
+				s.PresentAtHead = true
+				s.RemovedIn = ""
+			case r > a:
+				s.PresentAtHead = false
+				s.RemovedIn = commit
+			case a > 0:
+				// Moved around within the file.
+				s.PresentAtHead = true
+			}
+		}
+	}
+
+	return secrets
+}
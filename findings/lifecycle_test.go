@@ -0,0 +1,146 @@
+package findings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liminal-security/scan-action/git"
+)
+
+func TestTrack(t *testing.T) {
+	token := Finding{Origin: "GITHUB_API_TOKEN", Value: "ghp_BTqL****82UC7vz", File: "config.yml"}
+	key := Finding{Origin: "AWS_ACCESS_KEY", Value: "AKIA****MPLE", File: "main.py"}
+
+	at := func(f Finding, commit string, side git.Side) Finding {
+		f.Commit = commit
+		f.Side = side
+
+		return f
+	}
+	in := func(f Finding, file string) Finding {
+		f.File = file
+
+		return f
+	}
+
+	tests := []struct {
+		name     string
+		findings []Finding
+		commits  []string
+		want     []*Secret
+	}{
+		{
+			name:    "no findings",
+			commits: []string{"c1"},
+			want:    nil,
+		},
+		{
+			name:     "added and still present",
+			findings: []Finding{at(token, "c2", git.SideAdded)},
+			commits:  []string{"c3", "c2", "c1"},
+			want: []*Secret{
+				{
+					Fingerprint:   token.Fingerprint(),
+					Origin:        token.Origin,
+					Value:         token.Value,
+					File:          token.File,
+					IntroducedIn:  "c2",
+					PresentAtHead: true,
+					Occurrences:   []Occurrence{{Commit: "c2", Side: git.SideAdded}},
+				},
+			},
+		},
+		{
+			name: "added then removed",
+			findings: []Finding{
+				at(token, "c3", git.SideRemoved),
+				at(token, "c1", git.SideAdded),
+			},
+			commits: []string{"c3", "c2", "c1"},
+			want: []*Secret{
+				{
+					Fingerprint:  token.Fingerprint(),
+					Origin:       token.Origin,
+					Value:        token.Value,
+					File:         token.File,
+					IntroducedIn: "c1",
+					RemovedIn:    "c3",
+					Occurrences: []Occurrence{
+						{Commit: "c1", Side: git.SideAdded},
+						{Commit: "c3", Side: git.SideRemoved},
+					},
+				},
+			},
+		},
+		{
+			name: "pre-existing removed and another moved",
+			findings: []Finding{
+				at(key, "c1", git.SideRemoved),
+				at(key, "c1", git.SideAdded),
+				at(token, "c2", git.SideRemoved),
+			},
+			commits: []string{"c2", "c1"},
+			want: []*Secret{
+				{
+					Fingerprint:   key.Fingerprint(),
+					Origin:        key.Origin,
+					Value:         key.Value,
+					File:          key.File,
+					PresentAtHead: true,
+					Occurrences: []Occurrence{
+						{Commit: "c1", Side: git.SideRemoved},
+						{Commit: "c1", Side: git.SideAdded},
+					},
+				},
+				{
+					Fingerprint: token.Fingerprint(),
+					Origin:      token.Origin,
+					Value:       token.Value,
+					File:        token.File,
+					RemovedIn:   "c2",
+					Occurrences: []Occurrence{{Commit: "c2", Side: git.SideRemoved}},
+				},
+			},
+		},
+		{
+			name: "repeated across commits and files",
+			findings: []Finding{
+				at(token, "c3", git.SideAdded),
+				at(in(token, "other.yml"), "c2", git.SideAdded),
+				at(token, "c2", git.SideAdded),
+				at(token, "c1", git.SideAdded),
+			},
+			commits: []string{"c3", "c2", "c1"},
+			want: []*Secret{
+				{
+					Fingerprint:   token.Fingerprint(),
+					Origin:        token.Origin,
+					Value:         token.Value,
+					File:          token.File,
+					IntroducedIn:  "c1",
+					PresentAtHead: true,
+					Occurrences: []Occurrence{
+						{Commit: "c1", Side: git.SideAdded},
+						{Commit: "c2", Side: git.SideAdded},
+						{Commit: "c3", Side: git.SideAdded},
+					},
+				},
+				{
+					Fingerprint:   token.Fingerprint(),
+					Origin:        token.Origin,
+					Value:         token.Value,
+					File:          "other.yml",
+					IntroducedIn:  "c2",
+					PresentAtHead: true,
+					Occurrences:   []Occurrence{{Commit: "c2", Side: git.SideAdded}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Track(tt.findings, tt.commits))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package findings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity ranks how urgent a secret is.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = []string{"info", "low", "medium", "high", "critical"}
+
+func (s Severity) String() string {
+	if s < SeverityInfo || s > SeverityCritical {
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+
+	return severityNames[s]
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	parsed, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+
+	return nil
+}
+
+// ParseSeverity parses one of info, low, medium, high or critical.
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(n, strings.TrimSpace(name)) {
+			return Severity(i), nil
+		}
+	}
+
+	return SeverityInfo, fmt.Errorf("unknown severity %q, expected one of %s", name, strings.Join(severityNames, ", "))
+}
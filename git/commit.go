@@ -6,17 +6,59 @@ import (
 	"strings"
 )
 
+// Side tells whether a diff line was added or removed by a commit.
+type Side string
+
+const (
+	SideAdded   Side = "added"
+	SideRemoved Side = "removed"
+)
+
+// Line describes a single line of the diff data of a file.
+type Line struct {
+	Side Side
+	// Number is the line number in the file after the commit for added
+	// lines and before the commit for removed ones.
+	Number int
+}
+
+// Diff holds the changed lines of a commit per file. Data contains the
+// lines joined with "\n", Lines holds the matching per line metadata.
 type Diff struct {
-	Data map[string]string
+	Data  map[string]string
+	Lines map[string][]Line
+	// Duplicates maps the files whose change, from the same blob to the
+	// same blob, was diffed for another commit already to that commit and
+	// file. Their lines aren't in Data.
+	Duplicates map[string]FileRef
+}
+
+// FileRef points to a file changed by a commit.
+type FileRef struct {
+	Commit string
+	File   string
 }
 
 type Commit struct {
 	Hash string
-	Diff Diff
+	// Parent is the first parent the commit is diffed against, empty for
+	// root commits and patches.
+	Parent string
+	// Author is "Name <email>", empty if unknown.
+	Author string
+	Diff   Diff
 }
 
-func (c Commit) String() string {
-	var b strings.Builder
+// Location points to a line of the payload produced by Commit.String.
+type Location struct {
+	File string
+	// Line is the line number in the file, 0 if unknown.
+	Line int
+	Side Side
+}
+
+// Files returns the names of the changed files, sorted.
+func (c Commit) Files() []string {
 	fileNames := make([]string, 0, len(c.Diff.Data))
 
 	for fileName := range c.Diff.Data {
@@ -25,7 +67,26 @@ func (c Commit) String() string {
 
 	sort.Strings(fileNames)
 
-	for _, fileName := range fileNames {
+	return fileNames
+}
+
+// ChangedFiles returns the names of the changed files including the
+// duplicates, sorted.
+func (c Commit) ChangedFiles() []string {
+	fileNames := c.Files()
+	for fileName := range c.Diff.Duplicates {
+		fileNames = append(fileNames, fileName)
+	}
+
+	sort.Strings(fileNames)
+
+	return fileNames
+}
+
+func (c Commit) String() string {
+	var b strings.Builder
+
+	for _, fileName := range c.Files() {
 		b.WriteString(c.Diff.Data[fileName])
 		b.WriteString("\n")
 	}
@@ -34,30 +95,44 @@ func (c Commit) String() string {
 }
 
 func (c Commit) GetFileNameByLine(lineNum int) (fileName string, err error) {
-	if lineNum < 1 {
-		return "", fmt.Errorf("invalid line number %d", lineNum)
+	loc, err := c.Locate(lineNum)
+	if err != nil {
+		return "", err
 	}
 
-	fileNames := make([]string, 0, len(c.Diff.Data))
+	return loc.File, nil
+}
 
-	for fileName := range c.Diff.Data {
-		fileNames = append(fileNames, fileName)
+// Locate maps a line number of Commit.String output back to the file and
+// the side of the diff it came from.
+func (c Commit) Locate(lineNum int) (loc Location, err error) {
+	if lineNum < 1 {
+		return Location{}, fmt.Errorf("invalid line number %d", lineNum)
 	}
 
-	sort.Strings(fileNames)
-
 	totalNewLines := 0
 
-	for _, fileName := range fileNames {
+	for _, fileName := range c.Files() {
 		fileData := c.Diff.Data[fileName] + "\n"
 		newlineCount := strings.Count(fileData, "\n")
 
-		totalNewLines += newlineCount
+		if totalNewLines+newlineCount+1 > lineNum {
+			loc = Location{
+				File: fileName,
+				Side: SideAdded,
+			}
+
+			lines := c.Diff.Lines[fileName]
+			if idx := lineNum - totalNewLines - 1; idx < len(lines) {
+				loc.Line = lines[idx].Number
+				loc.Side = lines[idx].Side
+			}
 
-		if totalNewLines+1 > lineNum {
-			return fileName, nil
+			return loc, nil
 		}
+
+		totalNewLines += newlineCount
 	}
 
-	return "", fmt.Errorf("no data found for line number %d", lineNum)
+	return Location{}, fmt.Errorf("no data found for line number %d", lineNum)
 }
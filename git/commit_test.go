@@ -127,3 +127,65 @@ func TestCommitGetFileNameByLine(t *testing.T) {
 	}
 
 }
+
+func TestCommitLocate(t *testing.T) {
+	commit := Commit{
+		Hash: "539533aab24270f6201fcdd5aa25f6c16662ee58",
+		Diff: Diff{
+			Data: map[string]string{
+				"b.md": "# Notes\n# Notes\n\n## One more note",
+				"a.md": "Just some other file",
+			},
+			Lines: map[string][]Line{
+				"b.md": {
+					{Side: SideRemoved, Number: 1},
+					{Side: SideAdded, Number: 1},
+					{Side: SideAdded, Number: 2},
+					{Side: SideAdded, Number: 3},
+				},
+				"a.md": {{Side: SideAdded, Number: 7}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		lineNum int
+		want    Location
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "first file",
+			lineNum: 1,
+			want:    Location{File: "a.md", Line: 7, Side: SideAdded},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "removed line",
+			lineNum: 2,
+			want:    Location{File: "b.md", Line: 1, Side: SideRemoved},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "added line",
+			lineNum: 5,
+			want:    Location{File: "b.md", Line: 3, Side: SideAdded},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "out of range",
+			lineNum: 6,
+			want:    Location{},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := commit.Locate(tt.lineNum)
+			if !tt.wantErr(t, err, fmt.Sprintf("Locate(%v)", tt.lineNum)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Locate(%v)", tt.lineNum)
+		})
+	}
+}
@@ -1,81 +1,216 @@
 package git
 
 import (
-	"bufio"
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"log"
-	"os"
-	"path"
+	"log/slog"
+	"os/exec"
 	"reflect"
 	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/format/diff"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+
+	"github.com/liminal-security/scan-action/logging"
 )
 
+// ErrShallow is returned when a shallow clone lacks history the range
+// needs: its base isn't fetched or reachable, or its head is the shallow
+// boundary itself.
+var ErrShallow = errors.New("checkout is too shallow")
+
 type Differ struct {
+	path        string
+	gitDir      string
 	repo        *git.Repository
 	shallowEnds []string
+	logger      *slog.Logger
 }
 
-func NewDiffer(repoPath string) (differ *Differ, err error) {
-	gitRepo, err := git.PlainOpen(repoPath)
+// NewDiffer opens the repository at repoPath, a nil logger discards the logs.
+// repoPath is a checkout, including linked worktrees and submodules whose
+// .git is a file, or a git directory, like a bare repository or GIT_DIR.
+func NewDiffer(repoPath string, logger *slog.Logger) (differ *Differ, err error) {
+	d := &Differ{
+		path:   repoPath,
+		logger: logging.OrDiscard(logger),
+	}
+	if err := d.open(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (d *Differ) open() error {
+	// The common dir holds the objects, refs and shallow file of linked
+	// worktrees.
+	gitRepo, err := git.PlainOpenWithOptions(d.path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return fmt.Errorf("can't open repo %s: %s", d.path, err)
+	}
+
+	gitDir := d.path
+	if s, ok := gitRepo.Storer.(*filesystem.Storage); ok {
+		gitDir = s.Filesystem().Root()
+	}
+
+	shallow, err := gitRepo.Storer.Shallow()
 	if err != nil {
-		return nil, fmt.Errorf("can't open repo %s: %s", repoPath, err)
+		return fmt.Errorf("can't read shallow commits: %w", err)
+	}
+
+	shallowEnds := make([]string, 0, len(shallow))
+	for _, h := range shallow {
+		shallowEnds = append(shallowEnds, h.String())
 	}
 
-	shallowEnds, err := readShallow(repoPath)
+	d.logger.Debug("opened repository", "path", d.path, "git_dir", gitDir, "shallow_ends", shallowEnds)
+
+	d.repo = gitRepo
+	d.gitDir = gitDir
+	d.shallowEnds = shallowEnds
+
+	return nil
+}
+
+// Deepen fetches depth more commits of history from remote, like
+// git fetch --deepen, and reopens the repository.
+func (d *Differ) Deepen(ctx context.Context, remote string, depth int) error {
+	cmd := exec.CommandContext(ctx, "git", "--git-dir="+d.gitDir, "fetch", "--no-tags", "--deepen="+strconv.Itoa(depth), remote)
+	out, err := cmd.CombinedOutput()
 	if err != nil {
-		return nil, fmt.Errorf("can't read git/shallow: %w", err)
+		return fmt.Errorf("can't deepen from %s: %w: %s", remote, err, bytes.TrimSpace(out))
 	}
 
-	return &Differ{
-		repo:        gitRepo,
-		shallowEnds: shallowEnds,
-	}, nil
+	d.logger.Debug("deepened repository", "remote", remote, "depth", depth)
+
+	return d.open()
 }
 
-func readShallow(repoPath string) (commitsSHA []string, err error) {
-	file, err := os.OpenFile(path.Join(repoPath, ".git/shallow"), os.O_RDONLY, 0)
-	if errors.Is(err, os.ErrNotExist) {
-		return []string{}, nil
+// Complete checks that a shallow clone has the history the range needs,
+// it returns an error wrapping ErrShallow if not.
+func (d *Differ) Complete(r Range) error {
+	if len(d.shallowEnds) == 0 {
+		return nil
 	}
-	defer file.Close()
 
-	commitsSHA = []string{}
+	if r.Base != "" {
+		if _, err := d.repo.ResolveRevision(plumbing.Revision(r.Base)); err != nil {
+			return fmt.Errorf("base %s isn't fetched: %w", r.Base, ErrShallow)
+		}
+	}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		commitsSHA = append(commitsSHA, scanner.Text())
+	cIter, err := d.log(r)
+	if err != nil {
+		return err
 	}
+	defer cIter.Close()
+
+	first := true
+	var shallowErr error
+	err = cIter.ForEach(func(c *object.Commit) error {
+		isEnd := slices.Contains(d.shallowEnds, c.Hash.String())
+		switch {
+		case isEnd && first:
+			shallowErr = fmt.Errorf("head %s is the shallow boundary, there is nothing to diff it against: %w", c.Hash, ErrShallow)
+		case isEnd && r.Base != "":
+			shallowErr = fmt.Errorf("reached the shallow boundary at %s before base %s: %w", c.Hash, r.Base, ErrShallow)
+		}
+		first = false
+
+		// Like Walk, stop at the boundary, its parents aren't fetched.
+		if isEnd {
+			return storer.ErrStop
+		}
 
-	if err := scanner.Err(); err != nil {
-		return []string{}, fmt.Errorf("scan error: %w", err)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can't walk commits: %w", err)
 	}
 
-	return commitsSHA, scanner.Err()
+	return shallowErr
 }
 
+// Range limits the diffed commits like git log Base..Head does. An empty
+// Head means HEAD, an empty Base walks back to the root or shallow end.
+type Range struct {
+	Base string
+	Head string
+}
+
+// Diff diffs the commits reachable from HEAD.
 func (d *Differ) Diff() (commits []Commit, err error) {
-	cIter, err := d.repo.Log(&git.LogOptions{})
+	return d.DiffRange(Range{})
+}
+
+// DiffRange diffs the commits of the range, newest first. Use Walk to
+// avoid holding all of them in memory.
+func (d *Differ) DiffRange(r Range) (commits []Commit, err error) {
+	err = d.Walk(context.Background(), r, func(commit Commit) error {
+		commits = append(commits, commit)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+// Walk diffs the commits of the range one by one, newest first, and calls
+// fn with each of them. An error returned by fn stops the walk and is
+// returned as is.
+func (d *Differ) Walk(ctx context.Context, r Range, fn func(Commit) error) error {
+	if err := d.Complete(r); err != nil {
+		return err
+	}
+
+	cIter, err := d.log(r)
 	if err != nil {
-		return nil, fmt.Errorf("error creating commit iterator: %w", err)
+		return err
 	}
+	defer cIter.Close()
+
+	var fnErr error
+
+	// Blob changes diffed already, to the commit and file they were first
+	// diffed for.
+	seen := map[string]FileRef{}
 
 	err = cIter.ForEach(func(c *object.Commit) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Stop before processing shallow end commits (boundary of shallow fetch)
 		if slices.Contains(d.shallowEnds, c.Hash.String()) {
+			d.logger.Debug("reached shallow end", "commit", c.Hash.String())
+
 			return storer.ErrStop
 		}
 
 		commit := Commit{
-			Hash: c.Hash.String(),
+			Hash:   c.Hash.String(),
+			Author: fmt.Sprintf("%s <%s>", c.Author.Name, c.Author.Email),
+		}
+		if c.NumParents() > 0 {
+			commit.Parent = c.ParentHashes[0].String()
+		}
+		commitDiff := Diff{
+			Data:  map[string]string{},
+			Lines: map[string][]Line{},
 		}
-		commitDiff := Diff{}
-		commitDiff.Data = map[string]string{}
 
 		commitTree, err := c.Tree()
 		if err != nil {
@@ -87,40 +222,248 @@ func (d *Differ) Diff() (commits []Commit, err error) {
 			return fmt.Errorf("can't get commit parent %w", err)
 		}
 
-		patch, err := parentTree.Patch(commitTree)
+		changes, err := object.DiffTreeWithOptions(ctx, parentTree, commitTree, object.DefaultDiffTreeOptions)
 		if err != nil {
-			return fmt.Errorf("error getting patch: %w", err)
+			return fmt.Errorf("error getting changes: %w", err)
 		}
 
-		filePatches := patch.FilePatches()
-		for _, p := range filePatches {
-			filePath, err := getPath(p.Files())
-			if err != nil {
-				return fmt.Errorf("error getting file path: %w", err)
+		for _, change := range changes {
+			filePath := change.To.Name
+			if filePath == "" {
+				filePath = change.From.Name
 			}
 
-			var data string
-			chunks := p.Chunks()
-			for _, chunk := range chunks {
-				// Scan both additions and deletions, but skip unchanged context lines
-				if chunk.Type() == diff.Add || chunk.Type() == diff.Delete {
-					data += chunk.Content()
+			// The same blob change, e.g. of a revert or a copied branch,
+			// has the same lines, only diff and scan it once. Copies within
+			// a commit are diffed again, the findings of the commit aren't
+			// known before it is scanned.
+			blobs := change.From.TreeEntry.Hash.String() + ".." + change.To.TreeEntry.Hash.String()
+			ref, ok := seen[blobs]
+			if ok && ref.Commit != commit.Hash {
+				if commitDiff.Duplicates == nil {
+					commitDiff.Duplicates = map[string]FileRef{}
 				}
+				commitDiff.Duplicates[filePath] = ref
+
+				continue
 			}
+			if !ok {
+				seen[blobs] = FileRef{Commit: commit.Hash, File: filePath}
+			}
+
+			patch, err := change.Patch()
+			if err != nil {
+				return fmt.Errorf("error getting patch: %w", err)
+			}
+
+			for _, p := range patch.FilePatches() {
+				filePath, err := getPath(p.Files())
+				if err != nil {
+					return fmt.Errorf("error getting file path: %w", err)
+				}
 
-			commitDiff.Data[filePath] = data
+				data, lines := collectLines(p.Chunks())
+
+				commitDiff.Data[filePath] = data
+				commitDiff.Lines[filePath] = lines
+			}
 		}
 
 		commit.Diff = commitDiff
-		commits = append(commits, commit)
+
+		d.logger.Debug("diffed commit", "commit", commit.Hash, "files", len(commitDiff.Data), "duplicates", len(commitDiff.Duplicates))
+
+		if err := fn(commit); err != nil {
+			fnErr = err
+
+			return storer.ErrStop
+		}
 
 		return nil
 	})
+	if fnErr != nil {
+		return fnErr
+	}
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("can't diff commits: %w", err)
 	}
 
-	return commits, nil
+	return nil
+}
+
+func (d *Differ) log(r Range) (object.CommitIter, error) {
+	head := r.Head
+	if head == "" {
+		head = "HEAD"
+	}
+
+	headHash, err := d.repo.ResolveRevision(plumbing.Revision(head))
+	if err != nil {
+		return nil, fmt.Errorf("can't resolve head %s: %w", head, err)
+	}
+
+	headCommit, err := d.repo.CommitObject(*headHash)
+	if err != nil {
+		return nil, fmt.Errorf("can't get head commit %s: %w", headHash, err)
+	}
+
+	var excluded map[plumbing.Hash]bool
+	if r.Base != "" {
+		baseHash, err := d.repo.ResolveRevision(plumbing.Revision(r.Base))
+		if err != nil {
+			return nil, fmt.Errorf("can't resolve base %s: %w", r.Base, err)
+		}
+
+		// Like git log, exclude every ancestor of base, not only base
+		// itself: merged side branches reach them through other parents.
+		excluded, err = d.ancestors(*baseHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return object.NewCommitPreorderIter(headCommit, excluded, nil), nil
+}
+
+// MergeBase returns a best common ancestor of the commits a and b, like
+// git merge-base, the newest one when there are several. It returns an
+// error wrapping ErrShallow when a shallow clone lacks a or the common
+// history.
+func (d *Differ) MergeBase(a, b string) (string, error) {
+	aHash, err := d.repo.ResolveRevision(plumbing.Revision(a))
+	if err != nil {
+		if len(d.shallowEnds) > 0 {
+			return "", fmt.Errorf("%s isn't fetched: %w", a, ErrShallow)
+		}
+		return "", fmt.Errorf("can't resolve %s: %w", a, err)
+	}
+	bHash, err := d.repo.ResolveRevision(plumbing.Revision(b))
+	if err != nil {
+		return "", fmt.Errorf("can't resolve %s: %w", b, err)
+	}
+
+	common, err := d.ancestors(*aHash)
+	if err != nil {
+		return "", err
+	}
+
+	// Walk b's history down to the first common commits on every path.
+	var best *object.Commit
+	shallow := false
+	seen := map[plumbing.Hash]bool{}
+	stack := []plumbing.Hash{*bHash}
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+
+		c, err := d.repo.CommitObject(h)
+		if err != nil {
+			return "", fmt.Errorf("can't get commit %s: %w", h, err)
+		}
+		if common[h] {
+			if best == nil || c.Committer.When.After(best.Committer.When) {
+				best = c
+			}
+
+			continue
+		}
+		if slices.Contains(d.shallowEnds, h.String()) {
+			shallow = true
+
+			continue
+		}
+		stack = append(stack, c.ParentHashes...)
+	}
+
+	switch {
+	case best != nil:
+		return best.Hash.String(), nil
+	case shallow || len(d.shallowEnds) > 0:
+		return "", fmt.Errorf("no common history of %s and %s is fetched: %w", a, b, ErrShallow)
+	default:
+		return "", fmt.Errorf("%s and %s have no common history", a, b)
+	}
+}
+
+// ancestors returns hash and the commits reachable from it, down to the
+// shallow ends whose parents aren't fetched.
+func (d *Differ) ancestors(hash plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	seen := map[plumbing.Hash]bool{}
+	stack := []plumbing.Hash{hash}
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+
+		if slices.Contains(d.shallowEnds, h.String()) {
+			continue
+		}
+
+		c, err := d.repo.CommitObject(h)
+		if err != nil {
+			return nil, fmt.Errorf("can't get commit %s: %w", h, err)
+		}
+		stack = append(stack, c.ParentHashes...)
+	}
+
+	return seen, nil
+}
+
+// collectLines joins the added and removed lines of the chunks, skipping
+// unchanged context lines, and tags every line with its side and number.
+func collectLines(chunks []diff.Chunk) (data string, lines []Line) {
+	var b strings.Builder
+
+	oldLine, newLine := 1, 1
+
+	for _, chunk := range chunks {
+		content := chunk.Content()
+		if content == "" {
+			continue
+		}
+
+		chunkLines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+
+		var side Side
+
+		switch chunk.Type() {
+		case diff.Add:
+			side = SideAdded
+		case diff.Delete:
+			side = SideRemoved
+		default:
+			oldLine += len(chunkLines)
+			newLine += len(chunkLines)
+
+			continue
+		}
+
+		for _, line := range chunkLines {
+			if len(lines) > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(line)
+
+			number := newLine
+			if side == SideRemoved {
+				number = oldLine
+				oldLine++
+			} else {
+				newLine++
+			}
+
+			lines = append(lines, Line{Side: side, Number: number})
+		}
+	}
+
+	return b.String(), lines
 }
 
 func getParent(c *object.Commit) (tree *object.Tree, err error) {
@@ -1,9 +1,14 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/stretchr/testify/assert"
@@ -13,17 +18,28 @@ func TestOneCommit(t *testing.T) {
 	path := checkout(t, "testdata/scan-action-test", "e86f19f49a18854efdbc753d2cc7c266fdcf6b5f", "1", 2)
 	defer os.RemoveAll(path)
 
-	differ, err := NewDiffer(path)
+	differ, err := NewDiffer(path, nil)
 	if err != nil {
 		t.Fatalf("Can't create differ: %s", err)
 	}
 
 	expectedCommits := []Commit{
 		{
-			Hash: "e86f19f49a18854efdbc753d2cc7c266fdcf6b5f",
+			Hash:   "e86f19f49a18854efdbc753d2cc7c266fdcf6b5f",
+			Parent: "1a05c3eb00de25cf6cb10796dc569432e0a7a27f",
+			Author: fixtureAuthor,
 			Diff: Diff{
 				Data: map[string]string{
-					"README.md": "# scan-action-test# scan-action-test\n\n\nAdded new stuff",
+					"README.md": "# scan-action-test\n# scan-action-test\n\n\nAdded new stuff",
+				},
+				Lines: map[string][]Line{
+					"README.md": {
+						{Side: SideRemoved, Number: 1},
+						{Side: SideAdded, Number: 1},
+						{Side: SideAdded, Number: 2},
+						{Side: SideAdded, Number: 3},
+						{Side: SideAdded, Number: 4},
+					},
 				},
 			},
 		},
@@ -42,26 +58,41 @@ func TestMultipleCommits(t *testing.T) {
 	path := checkout(t, "testdata/scan-action-test", "539533aab24270f6201fcdd5aa25f6c16662ee58", "3", 3)
 	defer os.RemoveAll(path)
 
-	differ, err := NewDiffer(path)
+	differ, err := NewDiffer(path, nil)
 	if err != nil {
 		t.Fatalf("Can't create differ: %s", err)
 	}
 
 	expectedCommits := []Commit{
 		{
-			Hash: "539533aab24270f6201fcdd5aa25f6c16662ee58",
+			Hash:   "539533aab24270f6201fcdd5aa25f6c16662ee58",
+			Parent: "9006ae9c5d2b99c774da25f7b91bd7e8457b2275",
+			Author: fixtureAuthor,
 			Diff: Diff{
 				Data: map[string]string{
-					"notes.md": "# Notes# Notes\n\n## One more note",
+					"notes.md": "# Notes\n# Notes\n\n## One more note",
+				},
+				Lines: map[string][]Line{
+					"notes.md": {
+						{Side: SideRemoved, Number: 1},
+						{Side: SideAdded, Number: 1},
+						{Side: SideAdded, Number: 2},
+						{Side: SideAdded, Number: 3},
+					},
 				},
 			},
 		},
 		{
-			Hash: "9006ae9c5d2b99c774da25f7b91bd7e8457b2275",
+			Hash:   "9006ae9c5d2b99c774da25f7b91bd7e8457b2275",
+			Parent: "cf79a0c4802ab43e6a247ecc1d68d0ba59998133",
+			Author: fixtureAuthor,
 			Diff: Diff{
 				Data: map[string]string{
 					"notes.md": "# Notes",
 				},
+				Lines: map[string][]Line{
+					"notes.md": {{Side: SideAdded, Number: 1}},
+				},
 			},
 		},
 	}
@@ -74,6 +105,416 @@ func TestMultipleCommits(t *testing.T) {
 	assert.Equal(t, expectedCommits, commits)
 }
 
+func TestDiffRange(t *testing.T) {
+	path := checkout(t, "testdata/scan-action-test", "539533aab24270f6201fcdd5aa25f6c16662ee58", "3", 3)
+	defer os.RemoveAll(path)
+
+	differ, err := NewDiffer(path, nil)
+	if err != nil {
+		t.Fatalf("Can't create differ: %s", err)
+	}
+
+	tests := []struct {
+		name       string
+		r          Range
+		wantHashes []string
+		wantErr    bool
+	}{
+		{
+			name:       "base excluded",
+			r:          Range{Base: "9006ae9c5d2b99c774da25f7b91bd7e8457b2275"},
+			wantHashes: []string{"539533aab24270f6201fcdd5aa25f6c16662ee58"},
+		},
+		{
+			name:       "head and base",
+			r:          Range{Base: "cf79a0c4802ab43e6a247ecc1d68d0ba59998133", Head: "9006ae9c5d2b99c774da25f7b91bd7e8457b2275"},
+			wantHashes: []string{"9006ae9c5d2b99c774da25f7b91bd7e8457b2275"},
+		},
+		{
+			name:    "unknown base",
+			r:       Range{Base: "0000000000000000000000000000000000000001"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := differ.DiffRange(tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DiffRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var hashes []string
+			for _, c := range commits {
+				hashes = append(hashes, c.Hash)
+			}
+			assert.Equal(t, tt.wantHashes, hashes)
+		})
+	}
+
+	// A feature branch off b merging a side branch off a, b's parent, like
+	// the merge ref of a PR whose base branch moved on.
+	t.Run("merged side branch", func(t *testing.T) {
+		dir := t.TempDir()
+		commit := func(name string) string {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			gitOutput(t, dir, "add", name)
+			gitOutput(t, dir, "commit", "--quiet", "-m", name)
+
+			return gitOutput(t, dir, "rev-parse", "HEAD")
+		}
+
+		gitOutput(t, dir, "init", "--quiet", "--initial-branch=main")
+		a := commit("a.txt")
+		b := commit("b.txt")
+		gitOutput(t, dir, "checkout", "--quiet", "-b", "side", a)
+		x1 := commit("x1.txt")
+		gitOutput(t, dir, "checkout", "--quiet", "-b", "feature", b)
+		f1 := commit("f1.txt")
+		gitOutput(t, dir, "merge", "--quiet", "--no-ff", "-m", "merge", "side")
+		merge := gitOutput(t, dir, "rev-parse", "HEAD")
+
+		differ, err := NewDiffer(dir, nil)
+		if err != nil {
+			t.Fatalf("Can't create differ: %s", err)
+		}
+		commits, err := differ.DiffRange(Range{Base: b})
+		if err != nil {
+			t.Fatalf("DiffRange() error = %s", err)
+		}
+
+		want := strings.Fields(gitOutput(t, dir, "log", "--format=%H", b+"..HEAD"))
+		assert.ElementsMatch(t, want, commitHashes(commits))
+		assert.ElementsMatch(t, []string{merge, f1, x1}, commitHashes(commits))
+	})
+}
+
+func TestDiffDuplicates(t *testing.T) {
+	path := t.TempDir()
+	repo, err := git.PlainInit(path, false)
+	if err != nil {
+		t.Fatalf("Can't init repo: %s", err)
+	}
+
+	one, two := "token: one\n", "token: two\n"
+	c1 := commitFiles(t, repo, map[string]string{"a.txt": one})
+	c2 := commitFiles(t, repo, map[string]string{"a.txt": two})
+	c3 := commitFiles(t, repo, map[string]string{"a.txt": one})
+	c4 := commitFiles(t, repo, map[string]string{"a.txt": two, "b.txt": one})
+
+	differ, err := NewDiffer(path, nil)
+	if err != nil {
+		t.Fatalf("Can't create differ: %s", err)
+	}
+
+	commits, err := differ.Diff()
+	if err != nil {
+		t.Fatalf("Can't diff: %s", err)
+	}
+
+	changed := Diff{
+		Data:  map[string]string{"a.txt": "token: two\ntoken: one"},
+		Lines: map[string][]Line{"a.txt": {{Side: SideRemoved, Number: 1}, {Side: SideAdded, Number: 1}}},
+	}
+	expectedCommits := []Commit{
+		{
+			Hash:   c4,
+			Parent: c3,
+			Author: testAuthor,
+			Diff: Diff{
+				Data: map[string]string{"a.txt": "token: one\ntoken: two", "b.txt": "token: one"},
+				Lines: map[string][]Line{
+					"a.txt": {{Side: SideRemoved, Number: 1}, {Side: SideAdded, Number: 1}},
+					"b.txt": {{Side: SideAdded, Number: 1}},
+				},
+			},
+		},
+		{Hash: c3, Parent: c2, Author: testAuthor, Diff: changed},
+		{
+			Hash:   c2,
+			Parent: c1,
+			Author: testAuthor,
+			Diff: Diff{
+				Data:       map[string]string{},
+				Lines:      map[string][]Line{},
+				Duplicates: map[string]FileRef{"a.txt": {Commit: c4, File: "a.txt"}},
+			},
+		},
+		{
+			Hash:   c1,
+			Author: testAuthor,
+			Diff: Diff{
+				Data:       map[string]string{},
+				Lines:      map[string][]Line{},
+				Duplicates: map[string]FileRef{"a.txt": {Commit: c4, File: "b.txt"}},
+			},
+		},
+	}
+
+	assert.Equal(t, expectedCommits, commits)
+	assert.Equal(t, []string{"a.txt"}, commits[3].ChangedFiles())
+	assert.Empty(t, commits[3].String())
+
+	// Every walk starts afresh.
+	commits, err = differ.DiffRange(Range{Head: c2})
+	if err != nil {
+		t.Fatalf("Can't diff: %s", err)
+	}
+	assert.Equal(t, "token: one\ntoken: two", commits[0].Diff.Data["a.txt"])
+
+	// Copies within a commit, e.g. cp a b, are both diffed.
+	c5 := commitFiles(t, repo, map[string]string{"c.txt": "token: three\n", "d.txt": "token: three\n"})
+	commits, err = differ.DiffRange(Range{Base: c4})
+	if err != nil {
+		t.Fatalf("Can't diff: %s", err)
+	}
+	assert.Equal(t, []Commit{{
+		Hash:   c5,
+		Parent: c4,
+		Author: testAuthor,
+		Diff: Diff{
+			Data: map[string]string{"c.txt": "token: three", "d.txt": "token: three"},
+			Lines: map[string][]Line{
+				"c.txt": {{Side: SideAdded, Number: 1}},
+				"d.txt": {{Side: SideAdded, Number: 1}},
+			},
+		},
+	}}, commits)
+}
+
+func TestWalk(t *testing.T) {
+	path := t.TempDir()
+	repo, err := git.PlainInit(path, false)
+	if err != nil {
+		t.Fatalf("Can't init repo: %s", err)
+	}
+
+	c1 := commitFiles(t, repo, map[string]string{"a.txt": "one\n"})
+	c2 := commitFiles(t, repo, map[string]string{"a.txt": "two\n"})
+	commitFiles(t, repo, map[string]string{"a.txt": "three\n"})
+
+	differ, err := NewDiffer(path, nil)
+	if err != nil {
+		t.Fatalf("Can't create differ: %s", err)
+	}
+
+	errStop := errors.New("stop")
+	var hashes []string
+	err = differ.Walk(context.Background(), Range{Head: c2}, func(c Commit) error {
+		hashes = append(hashes, c.Hash)
+		if c.Hash == c2 {
+			return errStop
+		}
+
+		return nil
+	})
+	assert.Same(t, errStop, err)
+	assert.Equal(t, []string{c2}, hashes)
+
+	hashes = nil
+	err = differ.Walk(context.Background(), Range{Base: c1}, func(c Commit) error {
+		hashes = append(hashes, c.Hash)
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, hashes, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = differ.Walk(ctx, Range{}, func(Commit) error {
+		t.Fatal("walked a commit after cancellation")
+
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestShallowDeepen(t *testing.T) {
+	remote, hashes := bareRemote(t, 5)
+
+	// A depth 1 clone, like actions/checkout.
+	clone := filepath.Join(t.TempDir(), "clone")
+	runGit(t, "clone", "--quiet", "--depth=1", "file://"+remote, clone)
+
+	differ, err := NewDiffer(clone, nil)
+	if err != nil {
+		t.Fatalf("Can't create differ: %s", err)
+	}
+
+	_, err = differ.Diff()
+	assert.ErrorIs(t, err, ErrShallow)
+	assert.ErrorContains(t, err, "is the shallow boundary")
+
+	r := Range{Base: hashes[1]}
+	assert.ErrorContains(t, differ.Complete(r), "base "+hashes[1]+" isn't fetched")
+
+	if err := differ.Deepen(context.Background(), "origin", 2); err != nil {
+		t.Fatalf("Can't deepen: %s", err)
+	}
+	assert.ErrorIs(t, differ.Complete(r), ErrShallow)
+	assert.NoError(t, differ.Complete(Range{}))
+
+	if err := differ.Deepen(context.Background(), "origin", 1); err != nil {
+		t.Fatalf("Can't deepen: %s", err)
+	}
+	assert.NoError(t, differ.Complete(r))
+
+	commits, err := differ.DiffRange(r)
+	if err != nil {
+		t.Fatalf("Can't diff: %s", err)
+	}
+	assert.Equal(t, []string{hashes[4], hashes[3], hashes[2]}, commitHashes(commits))
+
+	assert.ErrorContains(t, differ.Deepen(context.Background(), "upstream", 1), "can't deepen from upstream")
+}
+
+func TestMergeBase(t *testing.T) {
+	src := t.TempDir()
+	commit := func(name string) string {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		gitOutput(t, src, "add", name)
+		gitOutput(t, src, "commit", "--quiet", "-m", name)
+
+		return gitOutput(t, src, "rev-parse", "HEAD")
+	}
+
+	// The destination branch moved on after feature forked from b.
+	gitOutput(t, src, "init", "--quiet", "--initial-branch=main")
+	commit("a.txt")
+	b := commit("b.txt")
+	gitOutput(t, src, "checkout", "--quiet", "-b", "feature")
+	commit("f1.txt")
+	f2 := commit("f2.txt")
+	gitOutput(t, src, "checkout", "--quiet", "main")
+	c := commit("c.txt")
+
+	differ, err := NewDiffer(src, nil)
+	if err != nil {
+		t.Fatalf("Can't create differ: %s", err)
+	}
+	got, err := differ.MergeBase(c, f2)
+	if err != nil {
+		t.Fatalf("MergeBase() error = %s", err)
+	}
+	assert.Equal(t, b, got)
+	assert.Equal(t, gitOutput(t, src, "merge-base", c, f2), got)
+
+	// A depth 1 clone of both branches lacks their common history.
+	clone := filepath.Join(t.TempDir(), "clone")
+	runGit(t, "clone", "--quiet", "--depth=1", "--no-single-branch", "--branch=feature", "file://"+src, clone)
+
+	differ, err = NewDiffer(clone, nil)
+	if err != nil {
+		t.Fatalf("Can't create differ: %s", err)
+	}
+	_, err = differ.MergeBase("refs/remotes/origin/main", "HEAD")
+	assert.ErrorIs(t, err, ErrShallow)
+	_, err = differ.MergeBase("refs/remotes/origin/release", "HEAD")
+	assert.ErrorIs(t, err, ErrShallow)
+
+	if err := differ.Deepen(context.Background(), "origin", 2); err != nil {
+		t.Fatalf("Can't deepen: %s", err)
+	}
+	got, err = differ.MergeBase("refs/remotes/origin/main", "HEAD")
+	if err != nil {
+		t.Fatalf("MergeBase() error = %s", err)
+	}
+	assert.Equal(t, b, got)
+}
+
+func TestDifferLayouts(t *testing.T) {
+	remote, hashes := bareRemote(t, 4)
+	url := "file://" + remote
+
+	tests := []struct {
+		name  string
+		setup func(dir string) string
+	}{
+		{
+			name: "checkout",
+			setup: func(dir string) string {
+				runGit(t, "clone", "--quiet", "--depth=2", url, dir)
+
+				return dir
+			},
+		},
+		{
+			name: "git dir",
+			setup: func(dir string) string {
+				// Like GIT_DIR pointing to the .git directory.
+				runGit(t, "clone", "--quiet", "--depth=2", url, dir)
+
+				return filepath.Join(dir, ".git")
+			},
+		},
+		{
+			name: "linked worktree",
+			setup: func(dir string) string {
+				clone := filepath.Join(dir, "clone")
+				runGit(t, "clone", "--quiet", "--depth=2", url, clone)
+
+				wt := filepath.Join(dir, "worktree")
+				runGit(t, "-C", clone, "worktree", "add", "--quiet", "--detach", wt)
+
+				return wt
+			},
+		},
+		{
+			name: "bare",
+			setup: func(dir string) string {
+				runGit(t, "clone", "--quiet", "--bare", "--depth=2", url, dir)
+
+				return dir
+			},
+		},
+		{
+			name: "git file",
+			setup: func(dir string) string {
+				// Like a submodule, .git is a file pointing to the git dir.
+				modules := filepath.Join(dir, "modules")
+				if err := os.Mkdir(modules, 0o755); err != nil {
+					t.Fatalf("can't create %s: %s", modules, err)
+				}
+
+				sub := filepath.Join(dir, "sub")
+				runGit(t, "clone", "--quiet", "--depth=2", "--separate-git-dir="+filepath.Join(modules, "sub"), url, sub)
+
+				return sub
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			differ, err := NewDiffer(tt.setup(t.TempDir()), nil)
+			if err != nil {
+				t.Fatalf("Can't create differ: %s", err)
+			}
+			assert.Equal(t, []string{hashes[2]}, differ.shallowEnds)
+
+			commits, err := differ.Diff()
+			if err != nil {
+				t.Fatalf("Can't diff: %s", err)
+			}
+			assert.Equal(t, []string{hashes[3]}, commitHashes(commits))
+
+			if err := differ.Deepen(context.Background(), "origin", 1); err != nil {
+				t.Fatalf("Can't deepen: %s", err)
+			}
+			assert.Equal(t, []string{hashes[1]}, differ.shallowEnds)
+
+			commits, err = differ.Diff()
+			if err != nil {
+				t.Fatalf("Can't diff: %s", err)
+			}
+			assert.Equal(t, []string{hashes[3], hashes[2]}, commitHashes(commits))
+		})
+	}
+}
+
 func TestGetPath(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,233 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// mboxFrom matches the line starting a patch of git format-patch output.
+var mboxFrom = regexp.MustCompile(`^From ([0-9a-f]{40}) `)
+
+// decoder decodes RFC 2047 encoded mail headers.
+var decoder = new(mime.WordDecoder)
+
+// hunkHeader matches @@ -old[,count] +new[,count] @@.
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatch parses unified diffs, like git diff output or a git
+// format-patch mbox series, into commits, newest first. Every patch of a
+// series is a commit named by the hash of its From line and authored by its
+// From header, a diff without one is a single commit named patch-1. The
+// changed lines are collected like Differ does, so they map back to files
+// and lines the same way.
+func ParsePatch(r io.Reader) ([]Commit, error) {
+	p := &patchParser{}
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			p.line(strings.TrimSuffix(line, "\n"))
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't read patch: %w", err)
+		}
+	}
+	p.endCommit()
+
+	slices.Reverse(p.commits)
+
+	return p.commits, nil
+}
+
+type patchParser struct {
+	commits []Commit
+	commit  *Commit
+
+	// oldPath is the path of the last --- line, a file starts when a +++
+	// line follows it.
+	oldPath *string
+
+	file    string
+	data    []string
+	lines   []Line
+	hasFile bool
+	oldLine int
+	newLine int
+	oldLeft int
+	newLeft int
+}
+
+func (p *patchParser) line(line string) {
+	if p.oldLeft > 0 || p.newLeft > 0 {
+		if p.hunkLine(line) {
+			return
+		}
+		// A truncated hunk, parse the line as a header.
+		p.oldLeft, p.newLeft = 0, 0
+	}
+
+	oldPath := p.oldPath
+	p.oldPath = nil
+
+	switch {
+	case mboxFrom.MatchString(line):
+		p.endCommit()
+		p.commit = &Commit{Hash: mboxFrom.FindStringSubmatch(line)[1]}
+	case strings.HasPrefix(line, "From: ") && p.commit != nil && p.commit.Author == "" && !p.hasFile:
+		author, err := decoder.DecodeHeader(line[6:])
+		if err != nil {
+			author = line[6:]
+		}
+		p.commit.Author = author
+	case strings.HasPrefix(line, "diff "):
+		p.endFile()
+	case strings.HasPrefix(line, "--- "):
+		path := patchPath(line[4:])
+		p.oldPath = &path
+	case strings.HasPrefix(line, "+++ ") && oldPath != nil:
+		p.startFile(*oldPath, patchPath(line[4:]))
+	case strings.HasPrefix(line, "@@ ") && p.hasFile:
+		m := hunkHeader.FindStringSubmatch(line)
+		if m == nil {
+			return
+		}
+		p.oldLine, p.oldLeft = hunkRange(m[1], m[2])
+		p.newLine, p.newLeft = hunkRange(m[3], m[4])
+	}
+}
+
+// hunkLine collects a line of a hunk, it returns false if the line doesn't
+// belong to one.
+func (p *patchParser) hunkLine(line string) bool {
+	var side Side
+
+	switch {
+	case strings.HasPrefix(line, "+") && p.newLeft > 0:
+		side = SideAdded
+	case strings.HasPrefix(line, "-") && p.oldLeft > 0:
+		side = SideRemoved
+	case strings.HasPrefix(line, `\`):
+		// \ No newline at end of file
+		return true
+	case (line == "" || strings.HasPrefix(line, " ")) && p.oldLeft > 0 && p.newLeft > 0:
+		// Some tools strip the space of empty context lines.
+		p.oldLine++
+		p.newLine++
+		p.oldLeft--
+		p.newLeft--
+
+		return true
+	default:
+		return false
+	}
+
+	number := p.newLine
+	if side == SideRemoved {
+		number = p.oldLine
+		p.oldLine++
+		p.oldLeft--
+	} else {
+		p.newLine++
+		p.newLeft--
+	}
+
+	p.data = append(p.data, line[1:])
+	p.lines = append(p.lines, Line{Side: side, Number: number})
+
+	return true
+}
+
+func (p *patchParser) startFile(oldPath, newPath string) {
+	p.endFile()
+
+	// Strip the a/ and b/ prefixes of git diff output.
+	if (strings.HasPrefix(oldPath, "a/") || oldPath == "") && (strings.HasPrefix(newPath, "b/") || newPath == "") {
+		oldPath = strings.TrimPrefix(oldPath, "a/")
+		newPath = strings.TrimPrefix(newPath, "b/")
+	}
+
+	p.file = newPath
+	if p.file == "" {
+		p.file = oldPath
+	}
+	p.hasFile = p.file != ""
+}
+
+func (p *patchParser) endFile() {
+	if p.hasFile && len(p.lines) > 0 {
+		if p.commit == nil {
+			p.commit = &Commit{Hash: "patch-" + strconv.Itoa(len(p.commits)+1)}
+		}
+		if p.commit.Diff.Data == nil {
+			p.commit.Diff = Diff{Data: map[string]string{}, Lines: map[string][]Line{}}
+		}
+
+		data := strings.Join(p.data, "\n")
+		if prev, ok := p.commit.Diff.Data[p.file]; ok {
+			data = prev + "\n" + data
+		}
+		p.commit.Diff.Data[p.file] = data
+		p.commit.Diff.Lines[p.file] = append(p.commit.Diff.Lines[p.file], p.lines...)
+	}
+
+	p.file, p.data, p.lines, p.hasFile = "", nil, nil, false
+	p.oldLeft, p.newLeft = 0, 0
+}
+
+func (p *patchParser) endCommit() {
+	p.endFile()
+
+	if p.commit != nil && p.commit.Diff.Data != nil {
+		p.commits = append(p.commits, *p.commit)
+	}
+	p.commit = nil
+}
+
+// patchPath parses the path of a --- or +++ line, "" for /dev/null.
+func patchPath(s string) string {
+	// diff -u appends the modification time after a tab.
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+
+	if strings.HasPrefix(s, `"`) {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			s = unquoted
+		}
+	}
+
+	if s == "/dev/null" {
+		return ""
+	}
+
+	return s
+}
+
+// hunkRange parses the start line and line count of a hunk side, the count
+// defaults to 1.
+func hunkRange(start, count string) (line, left int) {
+	line, _ = strconv.Atoi(start)
+	left = 1
+	if count != "" {
+		left, _ = strconv.Atoi(count)
+	}
+
+	// An empty side, e.g. of a new file, starts at line 0.
+	if left == 0 {
+		line++
+	}
+
+	return line, left
+}
@@ -0,0 +1,138 @@
+package git
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  []Commit
+	}{
+		{
+			name: "git diff",
+			patch: `diff --git a/config.env b/config.env
+index 3b18e51..a3c2f0e 100644
+--- a/config.env
++++ b/config.env
+@@ -1,3 +1,3 @@
+ NAME=app
+-TOKEN=old
++TOKEN=new
+ PORT=80
+\ No newline at end of file
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+`,
+			want: []Commit{{
+				Hash: "patch-1",
+				Diff: Diff{
+					Data: map[string]string{
+						"config.env": "TOKEN=old\nTOKEN=new",
+						"old.txt":    "gone",
+					},
+					Lines: map[string][]Line{
+						"config.env": {{Side: SideRemoved, Number: 2}, {Side: SideAdded, Number: 2}},
+						"old.txt":    {{Side: SideRemoved, Number: 1}},
+					},
+				},
+			}},
+		},
+		{
+			name: "diff -u",
+			patch: `--- app/config.orig	2024-01-01 10:00:00.000000000 +0000
++++ app/config	2024-01-01 10:00:01.000000000 +0000
+@@ -10,0 +11,2 @@
++user=admin
++password=hunter22
+`,
+			want: []Commit{{
+				Hash: "patch-1",
+				Diff: Diff{
+					Data:  map[string]string{"app/config": "user=admin\npassword=hunter22"},
+					Lines: map[string][]Line{"app/config": {{Side: SideAdded, Number: 11}, {Side: SideAdded, Number: 12}}},
+				},
+			}},
+		},
+		{
+			name: "quoted path and stripped context",
+			patch: `--- "a/with space.txt"
++++ "b/with space.txt"
+@@ -1,3 +1,3 @@
+ one
+
+-three
++3
+`,
+			want: []Commit{{
+				Hash: "patch-1",
+				Diff: Diff{
+					Data:  map[string]string{"with space.txt": "three\n3"},
+					Lines: map[string][]Line{"with space.txt": {{Side: SideRemoved, Number: 3}, {Side: SideAdded, Number: 3}}},
+				},
+			}},
+		},
+		{
+			name:  "no diff",
+			patch: "Just a message\n---\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePatch(strings.NewReader(tt.patch))
+			if err != nil {
+				t.Fatalf("ParsePatch() error = %s", err)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParsePatchMatchesDiffer(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("Can't init repo: %s", err)
+	}
+
+	commitFiles(t, repo, map[string]string{"a.txt": "one\ntwo\nthree\n", "b.txt": "keep\n"})
+	commitFiles(t, repo, map[string]string{"a.txt": "one\n2\nthree\nfour\n", "c.txt": "new\n-- \nnot a signature\n"})
+	commitFiles(t, repo, map[string]string{"b.txt": "", "c.txt": "From 0000000000000000000000000000000000000000 Mon Sep 17 00:00:00 2001\n"})
+
+	cmd := exec.Command("/usr/bin/git", "-C", dir, "format-patch", "--stdout", "--root", "HEAD")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("can't run %s: %s", cmd.String(), err)
+	}
+
+	got, err := ParsePatch(strings.NewReader(string(out)))
+	if err != nil {
+		t.Fatalf("ParsePatch() error = %s", err)
+	}
+
+	differ, err := NewDiffer(dir, nil)
+	if err != nil {
+		t.Fatalf("Can't create differ: %s", err)
+	}
+	want, err := differ.Diff()
+	if err != nil {
+		t.Fatalf("Can't diff: %s", err)
+	}
+	// Patches don't record the parents.
+	for i := range want {
+		want[i].Parent = ""
+	}
+
+	assert.Equal(t, want, got)
+}
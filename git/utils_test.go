@@ -5,7 +5,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
 func chdir(t *testing.T, dir string) func() {
@@ -71,3 +76,100 @@ func checkout(t *testing.T, repo string, commit string, pr string, depth int) (c
 
 	return tmpDir
 }
+
+// fixtureAuthor authors the commits of testdata/scan-action-test.
+const fixtureAuthor = "Gregory Man <gregory.man@entro.security>"
+
+// testAuthor authors the commits of commitFiles.
+const testAuthor = "test <test@example.com>"
+
+// commitFiles writes the files into the worktree of repo and commits them,
+// an empty content removes the file.
+func commitFiles(t *testing.T, repo *git.Repository, files map[string]string) string {
+	t.Helper()
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("can't get worktree: %s", err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(wt.Filesystem.Root(), name)
+		if content == "" {
+			if _, err := wt.Remove(name); err != nil {
+				t.Fatalf("can't remove %s: %s", name, err)
+			}
+
+			continue
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("can't write %s: %s", name, err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatalf("can't add %s: %s", name, err)
+		}
+	}
+
+	hash, err := wt.Commit("test", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("can't commit: %s", err)
+	}
+
+	return hash.String()
+}
+
+// bareRemote creates a bare repository with n commits changing a.txt and
+// returns its path and the commit hashes, oldest first.
+func bareRemote(t *testing.T, n int) (remote string, hashes []string) {
+	t.Helper()
+
+	src := t.TempDir()
+	repo, err := git.PlainInit(src, false)
+	if err != nil {
+		t.Fatalf("can't init repo: %s", err)
+	}
+
+	for i := range n {
+		hashes = append(hashes, commitFiles(t, repo, map[string]string{"a.txt": fmt.Sprintf("line %d\n", i)}))
+	}
+
+	remote = filepath.Join(t.TempDir(), "remote.git")
+	runGit(t, "clone", "--quiet", "--bare", src, remote)
+
+	return remote, hashes
+}
+
+func runGit(t *testing.T, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("/usr/bin/git", args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("can't run %s: %s\nOutput:\n%s", cmd.String(), err, out)
+	}
+}
+
+// gitOutput runs git in dir as the test author and returns its output
+// without the trailing newline.
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("/usr/bin/git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("can't run %s: %s\nOutput:\n%s", cmd.String(), err, out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+func commitHashes(commits []Commit) []string {
+	var hashes []string
+	for _, c := range commits {
+		hashes = append(hashes, c.Hash)
+	}
+
+	return hashes
+}
@@ -0,0 +1,66 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// MaxAnnotations is the number of annotations accepted per check run request.
+const MaxAnnotations = 50
+
+// Annotation levels of check runs.
+const (
+	AnnotationNotice  = "notice"
+	AnnotationWarning = "warning"
+	AnnotationFailure = "failure"
+)
+
+type Annotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"`
+	Message         string `json:"message"`
+	Title           string `json:"title,omitempty"`
+}
+
+type CheckRunOutput struct {
+	Title       string       `json:"title"`
+	Summary     string       `json:"summary"`
+	Text        string       `json:"text,omitempty"`
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+type CheckRun struct {
+	ID         int64           `json:"id,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	HeadSHA    string          `json:"head_sha,omitempty"`
+	Status     string          `json:"status,omitempty"`
+	Conclusion string          `json:"conclusion,omitempty"`
+	Output     *CheckRunOutput `json:"output,omitempty"`
+}
+
+// CreateCheckRun creates a check run, at most MaxAnnotations annotations
+// can be passed along.
+func (c *Client) CreateCheckRun(ctx context.Context, owner, repo string, run *CheckRun) (*CheckRun, error) {
+	var created CheckRun
+
+	path := fmt.Sprintf("/repos/%s/%s/check-runs", owner, repo)
+	if err := c.do(ctx, http.MethodPost, path, run, &created); err != nil {
+		return nil, fmt.Errorf("can't create check run: %w", err)
+	}
+
+	return &created, nil
+}
+
+// UpdateCheckRun updates a check run, annotations are appended to the
+// ones already uploaded.
+func (c *Client) UpdateCheckRun(ctx context.Context, owner, repo string, id int64, run *CheckRun) error {
+	path := fmt.Sprintf("/repos/%s/%s/check-runs/%d", owner, repo, id)
+	if err := c.do(ctx, http.MethodPatch, path, run, nil); err != nil {
+		return fmt.Errorf("can't update check run %d: %w", id, err)
+	}
+
+	return nil
+}
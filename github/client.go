@@ -0,0 +1,116 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// DefaultAPIURL is used when GITHUB_API_URL isn't set.
+const DefaultAPIURL = "https://api.github.com"
+
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("GitHub API HTTP code %d: %s", e.Code, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	var apiError *APIError
+	if errors.As(target, &apiError) {
+		return apiError.Code == e.Code
+	}
+
+	return false
+}
+
+// Client is a minimal GitHub REST API client.
+type Client struct {
+	baseURL string
+	token   string
+
+	httpClient *retryablehttp.Client
+}
+
+func NewClient(baseURL string, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+
+	retryClient := retryablehttp.NewClient()
+	retryClient.Logger = nil
+	retryClient.RetryMax = 2
+	retryClient.RetryWaitMin = 1 * time.Second
+	retryClient.RetryWaitMax = 5 * time.Second
+	retryClient.HTTPClient.Timeout = 30 * time.Second
+	retryClient.CheckRetry = retryablehttp.ErrorPropagatedRetryPolicy
+
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: retryClient,
+	}
+}
+
+// do sends in as JSON body, when set, and decodes the response into out,
+// when set.
+func (c *Client) do(ctx context.Context, method string, path string, in any, out any) error {
+	var body io.Reader
+	if in != nil {
+		buf := new(bytes.Buffer)
+		if err := json.NewEncoder(buf).Encode(in); err != nil {
+			return fmt.Errorf("can't encode request body: %w", err)
+		}
+		body = buf
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("can't create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("can't read response body: %w", err)
+		}
+
+		return &APIError{
+			Code:    resp.StatusCode,
+			Message: string(msg),
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("can't decode response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Event is the part of the workflow event payload (GITHUB_EVENT_PATH) the
+// scanner needs.
+type Event struct {
+	PullRequest *PullRequest `json:"pull_request"`
+}
+
+type PullRequest struct {
+	Number int `json:"number"`
+	Head   struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		SHA string `json:"sha"`
+	} `json:"base"`
+}
+
+// ReadEvent reads the event payload from path.
+func ReadEvent(path string) (*Event, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read event payload: %w", err)
+	}
+
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("can't decode event payload: %w", err)
+	}
+
+	return &event, nil
+}
+
+// SplitRepository splits GITHUB_REPOSITORY into owner and name.
+func SplitRepository(repository string) (owner string, name string, err error) {
+	owner, name, ok := strings.Cut(repository, "/")
+	if !ok || owner == "" || name == "" {
+		return "", "", fmt.Errorf("invalid repository %q, expected owner/name", repository)
+	}
+
+	return owner, name, nil
+}
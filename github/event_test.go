@@ -0,0 +1,38 @@
+package github
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event.json")
+	payload := `{"action":"synchronize","pull_request":{"number":7,"head":{"sha":"539533a"},"base":{"sha":"cf79a0c"}}}`
+	if err := os.WriteFile(path, []byte(payload), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := ReadEvent(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, event.PullRequest.Number)
+	assert.Equal(t, "539533a", event.PullRequest.Head.SHA)
+	assert.Equal(t, "cf79a0c", event.PullRequest.Base.SHA)
+
+	_, err = ReadEvent(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestSplitRepository(t *testing.T) {
+	owner, name, err := SplitRepository("liminal-security/scan-action")
+	assert.NoError(t, err)
+	assert.Equal(t, "liminal-security", owner)
+	assert.Equal(t, "scan-action", name)
+
+	for _, repo := range []string{"", "scan-action", "/scan-action", "liminal-security/"} {
+		_, _, err := SplitRepository(repo)
+		assert.Error(t, err, repo)
+	}
+}
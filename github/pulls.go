@@ -0,0 +1,113 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const perPage = 100
+
+// ReviewComment is an inline pull request review comment.
+type ReviewComment struct {
+	ID int64 `json:"id,omitempty"`
+	// CommitID is only set for comments created on their own.
+	CommitID string `json:"commit_id,omitempty"`
+	Path     string `json:"path"`
+	Line     int    `json:"line,omitempty"`
+	// Side is RIGHT for lines of the head, LEFT for lines of the base.
+	Side string `json:"side,omitempty"`
+	Body string `json:"body"`
+}
+
+// Review is a pull request review with its inline comments.
+type Review struct {
+	ID       int64           `json:"id,omitempty"`
+	CommitID string          `json:"commit_id,omitempty"`
+	Body     string          `json:"body"`
+	Event    string          `json:"event"`
+	Comments []ReviewComment `json:"comments,omitempty"`
+}
+
+// ListReviewComments returns all review comments of the pull request.
+func (c *Client) ListReviewComments(ctx context.Context, owner, repo string, number int) ([]ReviewComment, error) {
+	var all []ReviewComment
+
+	for page := 1; ; page++ {
+		var comments []ReviewComment
+
+		path := fmt.Sprintf("/repos/%s/%s/pulls/%d/comments?per_page=%d&page=%d", owner, repo, number, perPage, page)
+		if err := c.do(ctx, http.MethodGet, path, nil, &comments); err != nil {
+			return nil, fmt.Errorf("can't list review comments: %w", err)
+		}
+
+		all = append(all, comments...)
+		if len(comments) < perPage {
+			return all, nil
+		}
+	}
+}
+
+// ListReviews returns all reviews of the pull request.
+func (c *Client) ListReviews(ctx context.Context, owner, repo string, number int) ([]Review, error) {
+	var all []Review
+
+	for page := 1; ; page++ {
+		var reviews []Review
+
+		path := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews?per_page=%d&page=%d", owner, repo, number, perPage, page)
+		if err := c.do(ctx, http.MethodGet, path, nil, &reviews); err != nil {
+			return nil, fmt.Errorf("can't list reviews: %w", err)
+		}
+
+		all = append(all, reviews...)
+		if len(reviews) < perPage {
+			return all, nil
+		}
+	}
+}
+
+// CreateReviewComment creates a single inline comment on the commit
+// CommitID of the comment.
+func (c *Client) CreateReviewComment(ctx context.Context, owner, repo string, number int, comment *ReviewComment) (*ReviewComment, error) {
+	var created ReviewComment
+
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/comments", owner, repo, number)
+	if err := c.do(ctx, http.MethodPost, path, comment, &created); err != nil {
+		return nil, fmt.Errorf("can't create review comment: %w", err)
+	}
+
+	return &created, nil
+}
+
+// CreateReview creates a review with inline comments in one request.
+func (c *Client) CreateReview(ctx context.Context, owner, repo string, number int, review *Review) (*Review, error) {
+	var created Review
+
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews", owner, repo, number)
+	if err := c.do(ctx, http.MethodPost, path, review, &created); err != nil {
+		return nil, fmt.Errorf("can't create review: %w", err)
+	}
+
+	return &created, nil
+}
+
+// UpdateReview replaces the body of a review.
+func (c *Client) UpdateReview(ctx context.Context, owner, repo string, number int, id int64, body string) error {
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews/%d", owner, repo, number, id)
+	if err := c.do(ctx, http.MethodPut, path, map[string]string{"body": body}, nil); err != nil {
+		return fmt.Errorf("can't update review %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// UpdateReviewComment replaces the body of a review comment.
+func (c *Client) UpdateReviewComment(ctx context.Context, owner, repo string, id int64, body string) error {
+	path := fmt.Sprintf("/repos/%s/%s/pulls/comments/%d", owner, repo, id)
+	if err := c.do(ctx, http.MethodPatch, path, map[string]string{"body": body}, nil); err != nil {
+		return fmt.Errorf("can't update review comment %d: %w", id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Format of the log records.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat parses text or json.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(name))); f {
+	case FormatText, FormatJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q, expected text or json", name)
+	}
+}
+
+// ParseLevel parses debug, info, warn or error.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", name)
+	}
+
+	return level, nil
+}
+
+// New returns a logger writing records at or above level to w. In GitHub
+// Actions text records below level are still written, as ::debug::
+// commands shown when step debug logging is enabled.
+func New(w io.Writer, level slog.Level, format Format, github bool) *slog.Logger {
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+	}
+
+	// CI logs are timestamped already.
+	opts := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	}
+
+	visible := slog.NewTextHandler(w, opts)
+	if !github || level <= slog.LevelDebug {
+		return slog.New(visible)
+	}
+
+	debugOpts := *opts
+	debugOpts.Level = slog.LevelDebug
+
+	return slog.New(&githubHandler{
+		level:   level,
+		visible: visible,
+		debug:   slog.NewTextHandler(&commandWriter{w: w, command: "debug"}, &debugOpts),
+	})
+}
+
+// Discard returns a logger dropping all records.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// OrDiscard returns logger, or a discarding logger when it is nil.
+func OrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return Discard()
+	}
+
+	return logger
+}
+
+// Group wraps the following log lines into a collapsible group in GitHub
+// Actions, the returned func ends the group.
+func Group(w io.Writer, github bool, name string) (end func()) {
+	if !github {
+		return func() {}
+	}
+
+	fmt.Fprintf(w, "::group::%s\n", name)
+
+	return func() {
+		fmt.Fprintln(w, "::endgroup::")
+	}
+}
+
+// githubHandler sends records at or above level to visible and the ones
+// below to debug.
+type githubHandler struct {
+	level   slog.Level
+	visible slog.Handler
+	debug   slog.Handler
+}
+
+func (h *githubHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelDebug
+}
+
+func (h *githubHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.level {
+		return h.visible.Handle(ctx, r)
+	}
+
+	return h.debug.Handle(ctx, r)
+}
+
+func (h *githubHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &githubHandler{
+		level:   h.level,
+		visible: h.visible.WithAttrs(attrs),
+		debug:   h.debug.WithAttrs(attrs),
+	}
+}
+
+func (h *githubHandler) WithGroup(name string) slog.Handler {
+	return &githubHandler{
+		level:   h.level,
+		visible: h.visible.WithGroup(name),
+		debug:   h.debug.WithGroup(name),
+	}
+}
+
+// commandWriter prefixes every line with a workflow command.
+type commandWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	command string
+}
+
+func (c *commandWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var b bytes.Buffer
+	for _, line := range bytes.SplitAfter(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "::%s::%s", c.command, line)
+	}
+
+	if _, err := c.w.Write(b.Bytes()); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	level, err := ParseLevel("DEBUG")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, level)
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+
+	format, err := ParseFormat("json")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  slog.Level
+		format Format
+		github bool
+		want   string
+	}{
+		{
+			name:   "text",
+			level:  slog.LevelInfo,
+			format: FormatText,
+			want:   "level=INFO msg=\"scanning commit\" scan.commit=539533a\n",
+		},
+		{
+			name:   "text debug",
+			level:  slog.LevelDebug,
+			format: FormatText,
+			github: true,
+			want: "level=DEBUG msg=\"sending request\" scan.commit=539533a\n" +
+				"level=INFO msg=\"scanning commit\" scan.commit=539533a\n",
+		},
+		{
+			name:   "github",
+			level:  slog.LevelInfo,
+			format: FormatText,
+			github: true,
+			want: "::debug::level=DEBUG msg=\"sending request\" scan.commit=539533a\n" +
+				"level=INFO msg=\"scanning commit\" scan.commit=539533a\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(&buf, tt.level, tt.format, tt.github).WithGroup("scan").With("commit", "539533a")
+
+			logger.Debug("sending request")
+			logger.Info("scanning commit")
+
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestNewJSON(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, slog.LevelWarn, FormatJSON, true).Info("hidden")
+	New(&buf, slog.LevelWarn, FormatJSON, true).Warn("shown", "commit", "539533a")
+
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), `"level":"WARN","msg":"shown","commit":"539533a"}`)
+}
+
+func TestGroup(t *testing.T) {
+	var buf bytes.Buffer
+
+	end := Group(&buf, true, "Scanning commit 539533a")
+	buf.WriteString("inside\n")
+	end()
+	Group(&buf, false, "ignored")()
+
+	assert.Equal(t, "::group::Scanning commit 539533a\ninside\n::endgroup::\n", buf.String())
+}
@@ -0,0 +1,141 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/liminal-security/scan-action/findings"
+)
+
+// Exit codes of the scanner.
+const (
+	// ExitOK means no secret at or above the fail-on severity was found.
+	ExitOK = 0
+	// ExitError means the scan itself failed.
+	ExitError = 1
+	// ExitFindings means secrets at or above the fail-on severity were found.
+	ExitFindings = 2
+	// ExitShallow means the checkout lacks history the scanned range needs.
+	ExitShallow = 3
+	// ExitConfig means the scanner is misconfigured.
+	ExitConfig = 255
+)
+
+// Rule assigns a severity to origins matching a path.Match style pattern.
+type Rule struct {
+	Pattern  string
+	Severity findings.Severity
+}
+
+// DefaultRules rank cloud credentials and private keys the highest and
+// generic secrets the lowest. Origins matching no rule are medium.
+var DefaultRules = []Rule{
+	{Pattern: "AWS_*", Severity: findings.SeverityCritical},
+	{Pattern: "AZURE_*", Severity: findings.SeverityCritical},
+	{Pattern: "GCP_*", Severity: findings.SeverityCritical},
+	{Pattern: "GOOGLE_CLOUD_*", Severity: findings.SeverityCritical},
+	{Pattern: "*PRIVATE_KEY*", Severity: findings.SeverityCritical},
+	{Pattern: "GITHUB_*", Severity: findings.SeverityHigh},
+	{Pattern: "GITLAB_*", Severity: findings.SeverityHigh},
+	{Pattern: "SLACK_*", Severity: findings.SeverityHigh},
+	{Pattern: "STRIPE_*", Severity: findings.SeverityHigh},
+	{Pattern: "GENERIC*", Severity: findings.SeverityLow},
+}
+
+// Policy maps secrets to severities and decides which of them fail the scan.
+type Policy struct {
+	// Rules are evaluated in order, the first matching rule wins.
+	Rules []Rule
+	// FailOn is the lowest severity failing the scan.
+	FailOn findings.Severity
+	// VerifiedOnly only fails the scan on secrets verified to be live.
+	VerifiedOnly bool
+}
+
+// New returns a policy with the custom rules taking precedence over
+// DefaultRules.
+func New(rules []Rule, failOn findings.Severity) *Policy {
+	return &Policy{
+		Rules:  append(append([]Rule{}, rules...), DefaultRules...),
+		FailOn: failOn,
+	}
+}
+
+// ParseRules parses a comma separated list of PATTERN=SEVERITY pairs,
+// e.g. "GENERIC*=low,AWS_*=critical".
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		pattern, name, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid severity rule %q, expected PATTERN=SEVERITY", pair)
+		}
+
+		pattern = strings.TrimSpace(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+
+		severity, err := findings.ParseSeverity(name)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, Rule{Pattern: pattern, Severity: severity})
+	}
+
+	return rules, nil
+}
+
+// Severity of the secret. Secrets the scanned commits only removed are
+// informational, they are a reminder to rotate rather than a new leak.
+func (p *Policy) Severity(secret *findings.Secret) findings.Severity {
+	if !secret.Blocking() {
+		return findings.SeverityInfo
+	}
+
+	for _, rule := range p.Rules {
+		if ok, _ := path.Match(rule.Pattern, secret.Origin); ok {
+			return rule.Severity
+		}
+	}
+
+	return findings.SeverityMedium
+}
+
+// Apply assigns the severity of every secret and marks the ones failing
+// the scan, the reporters read both.
+func (p *Policy) Apply(secrets []*findings.Secret) {
+	for _, secret := range secrets {
+		secret.Severity = p.Severity(secret)
+		secret.Failing = p.Fails(secret)
+	}
+}
+
+// Fails reports whether the secret fails the scan. Apply must be called
+// first.
+func (p *Policy) Fails(secret *findings.Secret) bool {
+	if p.VerifiedOnly && secret.Verification != findings.VerificationVerified {
+		return false
+	}
+
+	return secret.Severity >= p.FailOn
+}
+
+// ExitCode for the scanned secrets. Apply must be called first.
+func (p *Policy) ExitCode(secrets []*findings.Secret) int {
+	for _, secret := range secrets {
+		if p.Fails(secret) {
+			return ExitFindings
+		}
+	}
+
+	return ExitOK
+}
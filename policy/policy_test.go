@@ -0,0 +1,138 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liminal-security/scan-action/findings"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []Rule
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "empty",
+			in:      "",
+			want:    nil,
+			wantErr: assert.NoError,
+		},
+		{
+			name: "multiple rules",
+			in:   "GENERIC*=low, AWS_*=Critical",
+			want: []Rule{
+				{Pattern: "GENERIC*", Severity: findings.SeverityLow},
+				{Pattern: "AWS_*", Severity: findings.SeverityCritical},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "missing severity",
+			in:      "GENERIC*",
+			wantErr: assert.Error,
+		},
+		{
+			name:    "unknown severity",
+			in:      "GENERIC*=urgent",
+			wantErr: assert.Error,
+		},
+		{
+			name:    "bad pattern",
+			in:      "[=low",
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRules(tt.in)
+			if !tt.wantErr(t, err) {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	present := func(origin string) *findings.Secret {
+		return &findings.Secret{Origin: origin, IntroducedIn: "c1", PresentAtHead: true}
+	}
+	removed := &findings.Secret{Origin: "AWS_ACCESS_KEY", RemovedIn: "c1"}
+
+	p := New([]Rule{{Pattern: "GITHUB_API_TOKEN", Severity: findings.SeverityLow}}, findings.SeverityHigh)
+
+	tests := []struct {
+		name         string
+		secret       *findings.Secret
+		wantSeverity findings.Severity
+		wantFails    bool
+	}{
+		{
+			name:         "cloud credential",
+			secret:       present("AWS_ACCESS_KEY"),
+			wantSeverity: findings.SeverityCritical,
+			wantFails:    true,
+		},
+		{
+			name:         "custom rule overrides default",
+			secret:       present("GITHUB_API_TOKEN"),
+			wantSeverity: findings.SeverityLow,
+			wantFails:    false,
+		},
+		{
+			name:         "generic",
+			secret:       present("GENERIC_PASSWORD"),
+			wantSeverity: findings.SeverityLow,
+			wantFails:    false,
+		},
+		{
+			name:         "no matching rule",
+			secret:       present("SOMETHING_ELSE"),
+			wantSeverity: findings.SeverityMedium,
+			wantFails:    false,
+		},
+		{
+			name:         "removed only",
+			secret:       removed,
+			wantSeverity: findings.SeverityInfo,
+			wantFails:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.Apply([]*findings.Secret{tt.secret})
+			assert.Equal(t, tt.wantSeverity, tt.secret.Severity)
+			assert.Equal(t, tt.wantFails, p.Fails(tt.secret))
+			assert.Equal(t, tt.wantFails, tt.secret.Failing)
+		})
+	}
+
+	secrets := []*findings.Secret{present("GENERIC_PASSWORD"), removed}
+	p.Apply(secrets)
+	assert.Equal(t, ExitOK, p.ExitCode(secrets))
+
+	secrets = append(secrets, present("AWS_ACCESS_KEY"))
+	p.Apply(secrets)
+	assert.Equal(t, ExitFindings, p.ExitCode(secrets))
+}
+
+func TestPolicyVerifiedOnly(t *testing.T) {
+	secret := func(verification findings.Verification) *findings.Secret {
+		return &findings.Secret{Origin: "GITHUB_API_TOKEN", IntroducedIn: "c1", PresentAtHead: true, Verification: verification}
+	}
+
+	p := New(nil, findings.SeverityLow)
+	p.VerifiedOnly = true
+
+	secrets := []*findings.Secret{secret(findings.VerificationInvalid), secret(findings.VerificationUnknown), secret("")}
+	p.Apply(secrets)
+	assert.Equal(t, ExitOK, p.ExitCode(secrets))
+
+	secrets = append(secrets, secret(findings.VerificationVerified))
+	p.Apply(secrets)
+	assert.Equal(t, ExitFindings, p.ExitCode(secrets))
+}
@@ -0,0 +1,109 @@
+// Package redact masks secrets in everything the scanner prints.
+package redact
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MinLength is the length below which values aren't registered, masking
+// them would mangle unrelated output.
+const MinLength = 6
+
+// DefaultVisible is the default number of leading characters left visible.
+const DefaultVisible = 4
+
+const stars = "****"
+
+// Redactor masks registered secrets. It is safe for concurrent use.
+type Redactor struct {
+	visible int
+	addMask io.Writer
+
+	mu      sync.RWMutex
+	secrets []string
+}
+
+// New returns a redactor leaving up to visible leading characters of a
+// secret readable. When addMask isn't nil, every registered secret is
+// written to it as a GitHub ::add-mask:: command, it has to be the raw
+// stdout, not a Writer of the redactor.
+func New(visible int, addMask io.Writer) *Redactor {
+	return &Redactor{
+		visible: max(visible, 0),
+		addMask: addMask,
+	}
+}
+
+// Add registers secrets, call it before they could be printed.
+func (r *Redactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, secret := range secrets {
+		if len(secret) < MinLength || slices.Contains(r.secrets, secret) {
+			continue
+		}
+
+		r.secrets = append(r.secrets, secret)
+		if r.addMask != nil {
+			// Workflow commands are line based, mask every line.
+			for line := range strings.Lines(secret) {
+				if line = strings.TrimRight(line, "\r\n"); len(line) >= MinLength {
+					fmt.Fprintf(r.addMask, "::add-mask::%s\n", line)
+				}
+			}
+		}
+	}
+
+	// Longest first, so a secret containing another one is masked whole.
+	slices.SortFunc(r.secrets, func(a, b string) int {
+		return len(b) - len(a)
+	})
+}
+
+// Mask keeps the leading visible characters of value, never more than a
+// quarter of it, and replaces the rest.
+func (r *Redactor) Mask(value string) string {
+	runes := []rune(value)
+	n := min(r.visible, len(runes)/4)
+
+	return string(runes[:n]) + stars
+}
+
+// String masks every registered secret in s.
+func (r *Redactor) String(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, secret := range r.secrets {
+		if strings.Contains(s, secret) {
+			s = strings.ReplaceAll(s, secret, r.Mask(secret))
+		}
+	}
+
+	return s
+}
+
+// Writer returns a writer masking registered secrets before writing to w.
+// Secrets split across two writes aren't masked, loggers and fmt write
+// whole lines at once.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return &writer{r: r, w: w}
+}
+
+type writer struct {
+	r *Redactor
+	w io.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.String(string(p))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
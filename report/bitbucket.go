@@ -0,0 +1,121 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/liminal-security/scan-action/bitbucket"
+	"github.com/liminal-security/scan-action/findings"
+)
+
+// File names of the Bitbucket Code Insights payloads.
+const (
+	BitbucketReportName      = "bitbucket-report.json"
+	BitbucketAnnotationsName = "bitbucket-annotations.json"
+)
+
+// BitbucketReportID is the Code Insights report id to upload the payloads to.
+const BitbucketReportID = "entro-secrets-scan"
+
+// WriteBitbucketReport writes the Code Insights report payload, to be PUT to
+// /2.0/repositories/{workspace}/{repo}/commit/{commit}/reports/BitbucketReportID.
+func WriteBitbucketReport(w io.Writer, result *Result) error {
+	return writeIndented(w, bitbucketReport(result))
+}
+
+// WriteBitbucketAnnotations writes the Code Insights annotations payload, to
+// be POSTed to the annotations of the report in chunks of
+// bitbucket.MaxAnnotations.
+func WriteBitbucketAnnotations(w io.Writer, result *Result) error {
+	return writeIndented(w, bitbucketAnnotations(result))
+}
+
+// CodeInsights uploads the result as the Code Insights report
+// BitbucketReportID of a commit, the annotations in chunks of
+// bitbucket.MaxAnnotations.
+type CodeInsights struct {
+	Client *bitbucket.Client
+	// Repository is the full name of the repository, workspace/repo.
+	Repository string
+	Commit     string
+}
+
+func (c *CodeInsights) Report(ctx context.Context, result *Result) error {
+	err := c.Client.PutReport(ctx, c.Repository, c.Commit, BitbucketReportID, bitbucketReport(result))
+	if err != nil {
+		return err
+	}
+
+	annotations := bitbucketAnnotations(result)
+	for len(annotations) > 0 {
+		batch := annotations[:min(len(annotations), bitbucket.MaxAnnotations)]
+		annotations = annotations[len(batch):]
+
+		if err := c.Client.CreateAnnotations(ctx, c.Repository, c.Commit, BitbucketReportID, batch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func bitbucketReport(result *Result) *bitbucket.Report {
+	status := "PASSED"
+	if len(result.Failing()) > 0 {
+		status = "FAILED"
+	}
+
+	return &bitbucket.Report{
+		Title:      "Entro secrets scan",
+		Details:    checkSummary(result),
+		ReportType: "SECURITY",
+		Reporter:   "Entro",
+		Result:     status,
+		Data: []bitbucket.ReportData{
+			{Title: "Secrets", Type: "NUMBER", Value: len(result.Secrets)},
+			{Title: "Failing secrets", Type: "NUMBER", Value: len(result.Failing())},
+			{Title: "Scanned commits", Type: "NUMBER", Value: len(result.Commits)},
+		},
+	}
+}
+
+func bitbucketAnnotations(result *Result) []bitbucket.Annotation {
+	annotations := make([]bitbucket.Annotation, 0, len(result.Secrets))
+	for _, s := range result.Secrets {
+		// Secrets not at a line of the head commit annotate their file.
+		line, _ := result.headLine(s)
+		annotations = append(annotations, bitbucket.Annotation{
+			ExternalID:     "entro-" + uuid(s.Key()),
+			AnnotationType: "VULNERABILITY",
+			Summary:        Message(s),
+			Severity:       bitbucketSeverity(s.Severity),
+			Path:           s.File,
+			Line:           line,
+		})
+	}
+
+	return annotations
+}
+
+// bitbucketSeverity maps to LOW, MEDIUM, HIGH or CRITICAL, Bitbucket has no
+// informational severity.
+func bitbucketSeverity(s findings.Severity) string {
+	if s == findings.SeverityInfo {
+		s = findings.SeverityLow
+	}
+
+	return strings.ToUpper(s.String())
+}
+
+func writeIndented(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("can't encode report: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liminal-security/scan-action/bitbucket"
+	"github.com/liminal-security/scan-action/findings"
+	"github.com/liminal-security/scan-action/git"
+)
+
+func TestWriteBitbucketReport(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteBitbucketReport(&buf, testResult())
+	assert.NoError(t, err)
+
+	want := `{
+  "title": "Entro secrets scan",
+  "details": "Scanned 2 commit(s): 2 secret(s) found, 0 commit(s) errored, 0 skipped.",
+  "report_type": "SECURITY",
+  "reporter": "Entro",
+  "result": "FAILED",
+  "data": [
+    {
+      "title": "Secrets",
+      "type": "NUMBER",
+      "value": 2
+    },
+    {
+      "title": "Failing secrets",
+      "type": "NUMBER",
+      "value": 1
+    },
+    {
+      "title": "Scanned commits",
+      "type": "NUMBER",
+      "value": 2
+    }
+  ]
+}
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestWriteBitbucketAnnotations(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteBitbucketAnnotations(&buf, testResult())
+	assert.NoError(t, err)
+
+	want := `[
+  {
+    "external_id": "entro-6659463e-90f0-8c36-d94a-68ae5fd1eff4",
+    "annotation_type": "VULNERABILITY",
+    "summary": "Found high GITHUB_API_TOKEN: ghp_BTqL****82UC7vz introduced in commit 9006ae9c5d2b99c774da25f7b91bd7e8457b2275, still present at HEAD",
+    "severity": "HIGH",
+    "path": "config/app.yml",
+    "line": 5
+  },
+  {
+    "external_id": "entro-6fbafbc3-15eb-e3be-1111-5bc29d8a9ed1",
+    "annotation_type": "VULNERABILITY",
+    "summary": "Removed GENERIC_PASSWORD: hun****2 in commit 539533aab24270f6201fcdd5aa25f6c16662ee58, it is still in git history, consider rotating it",
+    "severity": "LOW",
+    "path": "notes.md"
+  }
+]
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestCodeInsights(t *testing.T) {
+	var requests []string
+	var chunks []int
+	var report bitbucket.Report
+
+	// The fake API is reached through the Pipelines proxy, which sees the
+	// absolute URLs.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.String())
+
+		var err error
+		if r.Method == http.MethodPut {
+			err = json.NewDecoder(r.Body).Decode(&report)
+		} else {
+			var annotations []bitbucket.Annotation
+			err = json.NewDecoder(r.Body).Decode(&annotations)
+			chunks = append(chunks, len(annotations))
+		}
+		if err != nil {
+			t.Errorf("can't decode request: %s", err)
+		}
+	}))
+	defer proxy.Close()
+
+	client, err := bitbucket.NewClient("", proxy.URL)
+	if err != nil {
+		t.Fatalf("NewClient() error = %s", err)
+	}
+
+	result := &Result{
+		Commits: []Commit{{Hash: commit1, Status: StatusFindings, Findings: 250}},
+		FailOn:  findings.SeverityHigh,
+	}
+	for i := range 250 {
+		result.Secrets = append(result.Secrets, &findings.Secret{
+			Origin:        "GENERIC_PASSWORD",
+			Value:         fmt.Sprintf("pass****%d", i),
+			File:          "main.go",
+			IntroducedIn:  commit1,
+			PresentAtHead: true,
+			Occurrences:   []findings.Occurrence{{Commit: commit1, Line: i + 1, Side: git.SideAdded}},
+			Severity:      findings.SeverityLow,
+		})
+	}
+
+	insights := &CodeInsights{Client: client, Repository: "liminal/app", Commit: commit1}
+	assert.NoError(t, insights.Report(context.Background(), result))
+
+	reportURL := "http://api.bitbucket.org/2.0/repositories/liminal/app/commit/" + commit1 + "/reports/" + BitbucketReportID
+	assert.Equal(t, []string{
+		"PUT " + reportURL,
+		"POST " + reportURL + "/annotations",
+		"POST " + reportURL + "/annotations",
+		"POST " + reportURL + "/annotations",
+	}, requests)
+	assert.Equal(t, []int{100, 100, 50}, chunks)
+	assert.Equal(t, "PASSED", report.Result)
+}
@@ -0,0 +1,182 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/liminal-security/scan-action/findings"
+	"github.com/liminal-security/scan-action/github"
+	"github.com/liminal-security/scan-action/policy"
+)
+
+// maxOutputText is the limit of the check run output text.
+const maxOutputText = 65535
+
+// Checks reports the result as a check run, uploading all findings as
+// annotations in batches of github.MaxAnnotations.
+type Checks struct {
+	Client  *github.Client
+	Owner   string
+	Repo    string
+	HeadSHA string
+	// Name of the check run.
+	Name string
+}
+
+func (c *Checks) Report(ctx context.Context, result *Result) error {
+	var text bytes.Buffer
+	if err := WriteSummary(&text, result, ""); err != nil {
+		return err
+	}
+
+	// Annotations are placed on the head commit, secrets without a line
+	// there, e.g. removed ones, are listed in the summary instead.
+	annotations := make([]github.Annotation, 0, len(result.Secrets))
+	var unplaced []string
+	for _, s := range result.Secrets {
+		line, ok := result.headLine(s)
+		if !ok {
+			unplaced = append(unplaced, fmt.Sprintf("- %s %s secret %s in %s (%s)",
+				s.Severity, s.Origin, code(s.Value), code(s.File), lifecycle(s)))
+
+			continue
+		}
+		annotations = append(annotations, annotation(s, line, s.Failing))
+	}
+
+	summary := checkSummary(result)
+	if len(unplaced) > 0 {
+		summary += "\n\nNot at a line of the head commit:\n\n" + strings.Join(unplaced, "\n")
+	}
+
+	output := &github.CheckRunOutput{
+		Title:   checkTitle(result),
+		Summary: truncate(summary, maxOutputText),
+		Text:    truncate(text.String(), maxOutputText),
+	}
+
+	batch := annotations[:min(len(annotations), github.MaxAnnotations)]
+	annotations = annotations[len(batch):]
+
+	output.Annotations = batch
+
+	// Keep the run in progress until the last batch is uploaded.
+	run := &github.CheckRun{
+		Name:    c.Name,
+		HeadSHA: c.HeadSHA,
+		Status:  "in_progress",
+		Output:  output,
+	}
+	if len(annotations) == 0 {
+		run.Status = "completed"
+		run.Conclusion = conclusion(result)
+	}
+
+	created, err := c.Client.CreateCheckRun(ctx, c.Owner, c.Repo, run)
+	if err != nil {
+		return err
+	}
+
+	for len(annotations) > 0 {
+		batch = annotations[:min(len(annotations), github.MaxAnnotations)]
+		annotations = annotations[len(batch):]
+
+		update := &github.CheckRun{
+			Output: &github.CheckRunOutput{Title: output.Title, Summary: output.Summary, Annotations: batch},
+		}
+		if len(annotations) == 0 {
+			update.Status = "completed"
+			update.Conclusion = conclusion(result)
+		}
+
+		if err := c.Client.UpdateCheckRun(ctx, c.Owner, c.Repo, created.ID, update); err != nil {
+			c.abort(ctx, created.ID, result, output)
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// abort completes a check run whose annotations couldn't all be uploaded,
+// so it doesn't stay in progress and block required checks. Without
+// failing secrets it is neutral, the annotations are incomplete.
+func (c *Checks) abort(ctx context.Context, id int64, result *Result, output *github.CheckRunOutput) {
+	concl := conclusion(result)
+	if concl == "success" {
+		concl = "neutral"
+	}
+
+	update := &github.CheckRun{
+		Status:     "completed",
+		Conclusion: concl,
+		Output: &github.CheckRunOutput{
+			Title:   output.Title,
+			Summary: truncate(output.Summary+"\n\nSome annotations couldn't be uploaded, see the workflow log.", maxOutputText),
+		},
+	}
+	// Best effort, the failed update is returned.
+	_ = c.Client.UpdateCheckRun(context.WithoutCancel(ctx), c.Owner, c.Repo, id, update)
+}
+
+func conclusion(result *Result) string {
+	switch {
+	case result.ExitCode == policy.ExitFindings:
+		return "failure"
+	case result.Count(StatusError) > 0:
+		return "neutral"
+	default:
+		return "success"
+	}
+}
+
+func checkTitle(result *Result) string {
+	if failing := len(result.Failing()); failing > 0 {
+		return fmt.Sprintf("%d secret(s) at or above %s", failing, result.FailOn)
+	}
+
+	return fmt.Sprintf("No secrets at or above %s", result.FailOn)
+}
+
+func checkSummary(result *Result) string {
+	return fmt.Sprintf("Scanned %d commit(s): %d secret(s) found, %d commit(s) errored, %d skipped.",
+		len(result.Commits), len(result.Secrets), result.Count(StatusError), result.Count(StatusSkipped))
+}
+
+// annotation places the secret on its line at the head commit.
+func annotation(s *findings.Secret, line int, failing bool) github.Annotation {
+	annotationLevel := github.AnnotationWarning
+	switch level(s, failing) {
+	case "error":
+		annotationLevel = github.AnnotationFailure
+	case "notice":
+		annotationLevel = github.AnnotationNotice
+	}
+
+	return github.Annotation{
+		Path:            s.File,
+		StartLine:       line,
+		EndLine:         line,
+		AnnotationLevel: annotationLevel,
+		Title:           fmt.Sprintf("%s %s", s.Severity, s.Origin),
+		Message:         fmt.Sprintf("Found %s secret %s (%s)", s.Origin, s.Value, lifecycle(s)),
+	}
+}
+
+// truncate cuts s to at most maxLen bytes, without splitting a character.
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+
+	end := maxLen
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+
+	return s[:end]
+}
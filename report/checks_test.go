@@ -0,0 +1,146 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liminal-security/scan-action/findings"
+	"github.com/liminal-security/scan-action/git"
+	"github.com/liminal-security/scan-action/github"
+)
+
+func testChecks(url string) *Checks {
+	return &Checks{
+		Client:  github.NewClient(url, "gh-token"),
+		Owner:   "liminal-security",
+		Repo:    "scan-action-test",
+		HeadSHA: commit2,
+		Name:    "Entro secrets scan",
+	}
+}
+
+func TestChecks(t *testing.T) {
+	fake, svr := newFakeGitHub(t)
+
+	err := testChecks(svr.URL).Report(context.Background(), testResult())
+	assert.NoError(t, err)
+
+	assert.Len(t, fake.checkRuns, 1)
+	run := fake.checkRuns[0]
+	assert.Equal(t, "Entro secrets scan", run["name"])
+	assert.Equal(t, commit2, run["head_sha"])
+	assert.Equal(t, "completed", run["status"])
+	assert.Equal(t, "failure", run["conclusion"])
+
+	output := run["output"].(map[string]any)
+	assert.Equal(t, "1 secret(s) at or above low", output["title"])
+	// The removed secret has no line at the head commit.
+	assert.Equal(t, "Scanned 2 commit(s): 2 secret(s) found, 0 commit(s) errored, 0 skipped.\n\n"+
+		"Not at a line of the head commit:\n\n"+
+		"- info GENERIC_PASSWORD secret `hun****2` in `notes.md` (removed in `539533a`)", output["summary"])
+	assert.Contains(t, output["text"], "## Entro secrets scan")
+	assert.Equal(t, []any{
+		map[string]any{
+			"path":             "config/app.yml",
+			"start_line":       float64(5),
+			"end_line":         float64(5),
+			"annotation_level": "failure",
+			"title":            "high GITHUB_API_TOKEN",
+			"message":          "Found GITHUB_API_TOKEN secret ghp_BTqL****82UC7vz (introduced in `9006ae9`, present at HEAD)",
+		},
+	}, output["annotations"])
+}
+
+// lowSecrets returns a result with n low secrets on the lines of main.go,
+// none failing the scan.
+func lowSecrets(n int) *Result {
+	result := &Result{
+		Commits: []Commit{{Hash: commit1, Status: StatusFindings, Findings: n}},
+		FailOn:  findings.SeverityHigh,
+	}
+	for i := range n {
+		result.Secrets = append(result.Secrets, &findings.Secret{
+			Origin:        "GENERIC_PASSWORD",
+			Value:         fmt.Sprintf("pass****%d", i),
+			File:          "main.go",
+			IntroducedIn:  commit1,
+			PresentAtHead: true,
+			Occurrences:   []findings.Occurrence{{Commit: commit1, Line: i + 1, Side: git.SideAdded}},
+			Severity:      findings.SeverityLow,
+		})
+	}
+
+	return result
+}
+
+func TestChecksBatches(t *testing.T) {
+	fake, svr := newFakeGitHub(t)
+
+	err := testChecks(svr.URL).Report(context.Background(), lowSecrets(120))
+	assert.NoError(t, err)
+
+	assert.Len(t, fake.checkRuns, 3)
+	for i, want := range []struct {
+		status      string
+		annotations int
+		firstLine   float64
+	}{
+		{status: "in_progress", annotations: 50, firstLine: 1},
+		{status: "", annotations: 50, firstLine: 51},
+		{status: "completed", annotations: 20, firstLine: 101},
+	} {
+		run := fake.checkRuns[i]
+		annotations := run["output"].(map[string]any)["annotations"].([]any)
+		assert.Len(t, annotations, want.annotations)
+		assert.Equal(t, want.firstLine, annotations[0].(map[string]any)["start_line"])
+		assert.Equal(t, "warning", annotations[0].(map[string]any)["annotation_level"])
+
+		status, _ := run["status"].(string)
+		assert.Equal(t, want.status, status)
+	}
+	assert.Equal(t, "success", fake.checkRuns[2]["conclusion"])
+}
+
+func TestChecksBatchFails(t *testing.T) {
+	fake, svr := newFakeGitHub(t)
+	fake.failUpdate = 2
+
+	err := testChecks(svr.URL).Report(context.Background(), lowSecrets(160))
+	assert.Error(t, err)
+
+	// The run is completed rather than left in progress.
+	assert.Len(t, fake.checkRuns, 3)
+	last := fake.checkRuns[2]
+	assert.Equal(t, "completed", last["status"])
+	assert.Equal(t, "neutral", last["conclusion"])
+	assert.Contains(t, last["output"].(map[string]any)["summary"], "Some annotations couldn't be uploaded")
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s      string
+		maxLen int
+		want   string
+	}{
+		{s: "secret", maxLen: 10, want: "secret"},
+		{s: "secret", maxLen: 3, want: "sec"},
+		// "é" is 2 bytes, "🔑" 4.
+		{s: "clé", maxLen: 3, want: "cl"},
+		{s: "clé", maxLen: 4, want: "clé"},
+		{s: "a🔑b", maxLen: 4, want: "a"},
+		{s: "a🔑b", maxLen: 5, want: "a🔑"},
+	}
+	for _, tt := range tests {
+		got := truncate(tt.s, tt.maxLen)
+		if got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.maxLen, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) = %q isn't valid UTF-8", tt.s, tt.maxLen, got)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/liminal-security/scan-action/ci"
+	"github.com/liminal-security/scan-action/findings"
+)
+
+// Format selects how findings are printed and which native reports are
+// written.
+type Format string
+
+const (
+	FormatAuto      Format = "auto"
+	FormatText      Format = "text"
+	FormatGitHub    Format = "github"
+	FormatGitLab    Format = "gitlab"
+	FormatBitbucket Format = "bitbucket"
+	FormatAzure     Format = "azure"
+)
+
+var formats = []Format{FormatAuto, FormatText, FormatGitHub, FormatGitLab, FormatBitbucket, FormatAzure}
+
+// ParseFormat parses the name of a format.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range formats {
+		if string(f) == strings.ToLower(strings.TrimSpace(name)) {
+			return f, nil
+		}
+	}
+
+	names := make([]string, 0, len(formats))
+	for _, f := range formats {
+		names = append(names, string(f))
+	}
+
+	return "", fmt.Errorf("unknown format %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// Resolve turns FormatAuto into the format native to the CI provider.
+func (f Format) Resolve(provider ci.Provider) Format {
+	if f != FormatAuto {
+		return f
+	}
+
+	switch provider {
+	case ci.ProviderGitHub:
+		return FormatGitHub
+	case ci.ProviderGitLab:
+		return FormatGitLab
+	case ci.ProviderBitbucket:
+		return FormatBitbucket
+	case ci.ProviderAzure:
+		return FormatAzure
+	default:
+		return FormatText
+	}
+}
+
+// WriteFindings prints one line per secret in the format, GitHub and Azure
+// get logging commands turning into annotations. Secrets are placed at
+// their line of the head commit, secrets without one, e.g. removed ones,
+// only at their file.
+func WriteFindings(w io.Writer, result *Result, format Format) error {
+	for _, s := range result.Secrets {
+		headLine, _ := result.headLine(s)
+
+		var line string
+		switch format {
+		case FormatGitHub:
+			line = gitHubCommand(s, headLine, s.Failing)
+		case FormatAzure:
+			line = azureCommand(s, headLine, s.Failing)
+		default:
+			line = textLine(s, headLine, s.Failing)
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return fmt.Errorf("can't write findings: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Message describes the secret, where it is in the history and whether it
+// was verified.
+func Message(s *findings.Secret) string {
+	message := historyMessage(s)
+	if v := verification(s); v != "" {
+		message += ", " + v
+	}
+
+	return message
+}
+
+func historyMessage(s *findings.Secret) string {
+	switch {
+	case s.PresentAtHead:
+		introduced := "an earlier commit"
+		if s.IntroducedIn != "" {
+			introduced = "commit " + s.IntroducedIn
+		}
+
+		return fmt.Sprintf("Found %s %s: %s introduced in %s, still present at HEAD", s.Severity, s.Origin, s.Value, introduced)
+	case s.IntroducedIn != "":
+		return fmt.Sprintf("Found %s %s: %s introduced in commit %s, removed in commit %s, still in git history", s.Severity, s.Origin, s.Value, s.IntroducedIn, s.RemovedIn)
+	default:
+		return fmt.Sprintf("Removed %s: %s in commit %s, it is still in git history, consider rotating it", s.Origin, s.Value, s.RemovedIn)
+	}
+}
+
+// level of the secret: error when failing the scan, notice for secrets
+// only removed and warning otherwise.
+func level(s *findings.Secret, failing bool) string {
+	switch {
+	case failing:
+		return "error"
+	case s.Severity == findings.SeverityInfo:
+		return "notice"
+	default:
+		return "warning"
+	}
+}
+
+func textLine(s *findings.Secret, line int, failing bool) string {
+	loc := s.File
+	if line > 0 {
+		loc = fmt.Sprintf("%s:%d", s.File, line)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", strings.ToUpper(level(s, failing)), loc, Message(s))
+}
+
+var (
+	gitHubMessage  = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	gitHubProperty = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func gitHubCommand(s *findings.Secret, line int, failing bool) string {
+	props := "file=" + gitHubProperty.Replace(s.File)
+	if line > 0 {
+		props += fmt.Sprintf(",line=%d", line)
+	}
+
+	return fmt.Sprintf("::%s %s::%s", level(s, failing), props, gitHubMessage.Replace(Message(s)))
+}
+
+var (
+	azureMessage  = strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A")
+	azureProperty = strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A", ";", "%3B", "]", "%5D")
+)
+
+func azureCommand(s *findings.Secret, line int, failing bool) string {
+	// Azure only knows errors and warnings.
+	issueType := "warning"
+	if failing {
+		issueType = "error"
+	}
+
+	props := fmt.Sprintf("type=%s;sourcepath=%s;", issueType, azureProperty.Replace(s.File))
+	if line > 0 {
+		props += fmt.Sprintf("linenumber=%d;", line)
+	}
+	props += fmt.Sprintf("code=%s;", azureProperty.Replace(s.Origin))
+
+	return fmt.Sprintf("##vso[task.logissue %s]%s", props, azureMessage.Replace(Message(s)))
+}
@@ -0,0 +1,104 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liminal-security/scan-action/ci"
+	"github.com/liminal-security/scan-action/findings"
+	"github.com/liminal-security/scan-action/policy"
+)
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat(" Azure ")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatAzure, f)
+
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestFormatResolve(t *testing.T) {
+	assert.Equal(t, FormatGitHub, FormatAuto.Resolve(ci.ProviderGitHub))
+	assert.Equal(t, FormatGitLab, FormatAuto.Resolve(ci.ProviderGitLab))
+	assert.Equal(t, FormatBitbucket, FormatAuto.Resolve(ci.ProviderBitbucket))
+	assert.Equal(t, FormatAzure, FormatAuto.Resolve(ci.ProviderAzure))
+	assert.Equal(t, FormatText, FormatAuto.Resolve(ci.ProviderNone))
+	assert.Equal(t, FormatText, FormatText.Resolve(ci.ProviderGitHub))
+}
+
+func TestWriteFindingsVerifiedOnly(t *testing.T) {
+	result := testResult()
+	result.VerifiedOnly = true
+	result.Secrets[0].Verification = findings.VerificationInvalid
+
+	// The reporters follow the secrets the policy marks as failing.
+	p := policy.New([]policy.Rule{{Pattern: "GITHUB_API_TOKEN", Severity: findings.SeverityHigh}}, findings.SeverityLow)
+	p.VerifiedOnly = true
+	p.Apply(result.Secrets)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteFindings(&buf, result, FormatText))
+	assert.Contains(t, buf.String(), "WARNING: config/app.yml:5: Found high GITHUB_API_TOKEN: ghp_BTqL****82UC7vz introduced in commit 9006ae9c5d2b99c774da25f7b91bd7e8457b2275, still present at HEAD, verified invalid\n")
+	assert.Empty(t, result.Failing())
+
+	result.Secrets[0].Verification = findings.VerificationVerified
+	p.Apply(result.Secrets)
+	buf.Reset()
+	assert.NoError(t, WriteFindings(&buf, result, FormatText))
+	assert.Contains(t, buf.String(), "ERROR: config/app.yml:5: Found high GITHUB_API_TOKEN: ghp_BTqL****82UC7vz introduced in commit 9006ae9c5d2b99c774da25f7b91bd7e8457b2275, still present at HEAD, verified live\n")
+	assert.Len(t, result.Failing(), 1)
+}
+
+func TestWriteFindings(t *testing.T) {
+	result := testResult()
+	result.Secrets[0].File = "config/app,prod.yml"
+
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{
+			format: FormatGitHub,
+			want: "::error file=config/app%2Cprod.yml,line=5::Found high GITHUB_API_TOKEN: ghp_BTqL****82UC7vz introduced in commit 9006ae9c5d2b99c774da25f7b91bd7e8457b2275, still present at HEAD\n" +
+				"::notice file=notes.md::Removed GENERIC_PASSWORD: hun****2 in commit 539533aab24270f6201fcdd5aa25f6c16662ee58, it is still in git history, consider rotating it\n",
+		},
+		{
+			format: FormatAzure,
+			want: "##vso[task.logissue type=error;sourcepath=config/app,prod.yml;linenumber=5;code=GITHUB_API_TOKEN;]Found high GITHUB_API_TOKEN: ghp_BTqL****82UC7vz introduced in commit 9006ae9c5d2b99c774da25f7b91bd7e8457b2275, still present at HEAD\n" +
+				"##vso[task.logissue type=warning;sourcepath=notes.md;code=GENERIC_PASSWORD;]Removed GENERIC_PASSWORD: hun****2 in commit 539533aab24270f6201fcdd5aa25f6c16662ee58, it is still in git history, consider rotating it\n",
+		},
+		{
+			format: FormatText,
+			want: "ERROR: config/app,prod.yml:5: Found high GITHUB_API_TOKEN: ghp_BTqL****82UC7vz introduced in commit 9006ae9c5d2b99c774da25f7b91bd7e8457b2275, still present at HEAD\n" +
+				"NOTICE: notes.md: Removed GENERIC_PASSWORD: hun****2 in commit 539533aab24270f6201fcdd5aa25f6c16662ee58, it is still in git history, consider rotating it\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteFindings(&buf, result, tt.format)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestWriteFindingsMovedSecret(t *testing.T) {
+	result := testResult()
+	result.Secrets = result.Secrets[:1]
+
+	// The token was added at line 3 and line 5 of config/app.yml, a newer
+	// commit moving it leaves no known line at HEAD.
+	result.Commits[0].Files = []string{"config/app.yml"}
+	result.Commits = append([]Commit{{Hash: "cf79a0c4802ab43e6a247ecc1d68d0ba59998133", Files: []string{"config/app.yml"}}}, result.Commits...)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteFindings(&buf, result, FormatGitHub))
+	assert.True(t, strings.HasPrefix(buf.String(), "::error file=config/app.yml::"), buf.String())
+
+	assert.Zero(t, bitbucketAnnotations(result)[0].Line)
+}
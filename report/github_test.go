@@ -0,0 +1,245 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGitHub is a local stand-in for the parts of the GitHub REST API the
+// reporters use.
+type fakeGitHub struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	nextID   int64
+	comments map[int64]map[string]any
+	reviews  []map[string]any
+	// reviewUpdates counts the updates of review bodies.
+	reviewUpdates int
+	// outsideDiff is a path whose lines can't be commented on, reviews
+	// with comments on it are rejected as a whole.
+	outsideDiff string
+	// checkRuns holds the create request followed by the updates.
+	checkRuns []map[string]any
+	// failUpdate rejects the nth update of a check run, counting from 1.
+	failUpdate int
+	updates    int
+}
+
+func newFakeGitHub(t *testing.T) (*fakeGitHub, *httptest.Server) {
+	f := &fakeGitHub{
+		t:        t,
+		comments: map[int64]map[string]any{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/{owner}/{repo}/pulls/{number}/comments", f.listComments)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/pulls/{number}/reviews", f.listReviews)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/pulls/{number}/reviews", f.createReview)
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/pulls/{number}/reviews/{id}", f.updateReview)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/pulls/{number}/comments", f.createComment)
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/pulls/comments/{id}", f.updateComment)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/check-runs", f.checkRun)
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/check-runs/{id}", f.checkRun)
+
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer gh-token" {
+			t.Errorf("expected Authorization header %q, got %q", "Bearer gh-token", got)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(svr.Close)
+
+	return f, svr
+}
+
+func (f *fakeGitHub) id() int64 {
+	f.nextID++
+
+	return f.nextID
+}
+
+func (f *fakeGitHub) listComments(w http.ResponseWriter, _ *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	comments := []map[string]any{}
+	for i := int64(1); i <= f.nextID; i++ {
+		if c, ok := f.comments[i]; ok {
+			comments = append(comments, c)
+		}
+	}
+
+	writeJSON(f.t, w, http.StatusOK, comments)
+}
+
+func (f *fakeGitHub) createReview(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var review map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		f.t.Fatalf("can't decode review: %s", err)
+	}
+
+	comments, _ := review["comments"].([]any)
+	for _, c := range comments {
+		if comment, _ := c.(map[string]any); comment["path"] == f.outsideDiff {
+			writeJSON(f.t, w, http.StatusUnprocessableEntity, map[string]string{"message": "line must be part of the diff"})
+
+			return
+		}
+	}
+
+	for _, c := range comments {
+		comment, _ := c.(map[string]any)
+		id := f.id()
+		comment["id"] = id
+		f.comments[id] = comment
+	}
+
+	review["id"] = f.id()
+	f.reviews = append(f.reviews, review)
+
+	writeJSON(f.t, w, http.StatusOK, review)
+}
+
+func (f *fakeGitHub) listReviews(w http.ResponseWriter, _ *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reviews := []map[string]any{}
+	reviews = append(reviews, f.reviews...)
+
+	writeJSON(f.t, w, http.StatusOK, reviews)
+}
+
+func (f *fakeGitHub) updateReview(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var id int64
+	if _, err := fmt.Sscan(r.PathValue("id"), &id); err != nil {
+		f.t.Fatalf("invalid review id %q", r.PathValue("id"))
+	}
+
+	for _, review := range f.reviews {
+		if review["id"] != id {
+			continue
+		}
+
+		var update map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			f.t.Fatalf("can't decode review update: %s", err)
+		}
+		review["body"] = update["body"]
+		f.reviewUpdates++
+
+		writeJSON(f.t, w, http.StatusOK, review)
+
+		return
+	}
+
+	writeJSON(f.t, w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+}
+
+func (f *fakeGitHub) createComment(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var comment map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+		f.t.Fatalf("can't decode comment: %s", err)
+	}
+	if comment["path"] == f.outsideDiff {
+		writeJSON(f.t, w, http.StatusUnprocessableEntity, map[string]string{"message": "line must be part of the diff"})
+
+		return
+	}
+
+	id := f.id()
+	comment["id"] = id
+	f.comments[id] = comment
+
+	writeJSON(f.t, w, http.StatusCreated, comment)
+}
+
+func (f *fakeGitHub) updateComment(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var id int64
+	if _, err := fmt.Sscan(r.PathValue("id"), &id); err != nil {
+		f.t.Fatalf("invalid comment id %q", r.PathValue("id"))
+	}
+
+	comment, ok := f.comments[id]
+	if !ok {
+		writeJSON(f.t, w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+
+		return
+	}
+
+	var update map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		f.t.Fatalf("can't decode comment update: %s", err)
+	}
+	comment["body"] = update["body"]
+
+	writeJSON(f.t, w, http.StatusOK, comment)
+}
+
+func (f *fakeGitHub) checkRun(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if id := r.PathValue("id"); id != "" && id != "1" {
+		writeJSON(f.t, w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+
+		return
+	}
+	if r.Method == http.MethodPatch {
+		f.updates++
+		if f.updates == f.failUpdate {
+			writeJSON(f.t, w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+
+			return
+		}
+	}
+
+	var run map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&run); err != nil {
+		f.t.Fatalf("can't decode check run: %s", err)
+	}
+	f.checkRuns = append(f.checkRuns, run)
+
+	writeJSON(f.t, w, http.StatusOK, map[string]any{"id": 1})
+}
+
+func (f *fakeGitHub) commentBodies() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var bodies []string
+	for i := int64(1); i <= f.nextID; i++ {
+		if c, ok := f.comments[i]; ok {
+			body, _ := c["body"].(string)
+			bodies = append(bodies, strings.SplitN(body, "\n", 2)[0])
+		}
+	}
+
+	return bodies
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("can't encode response: %s", err)
+	}
+}
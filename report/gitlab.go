@@ -0,0 +1,155 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/liminal-security/scan-action/findings"
+)
+
+// GitLabReportName is the file name GitLab expects for secret detection
+// report artifacts.
+const GitLabReportName = "gl-secret-detection-report.json"
+
+// gitLabSchemaVersion of the GitLab security report schema.
+const gitLabSchemaVersion = "15.0.7"
+
+// gitLabTimeFormat is the time format of the report schema.
+const gitLabTimeFormat = "2006-01-02T15:04:05"
+
+type gitLabReport struct {
+	Version         string                `json:"version"`
+	Vulnerabilities []gitLabVulnerability `json:"vulnerabilities"`
+	Scan            gitLabScan            `json:"scan"`
+}
+
+type gitLabVulnerability struct {
+	ID          string             `json:"id"`
+	Category    string             `json:"category"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Severity    string             `json:"severity"`
+	Scanner     gitLabScanner      `json:"scanner"`
+	Location    gitLabLocation     `json:"location"`
+	Identifiers []gitLabIdentifier `json:"identifiers"`
+}
+
+type gitLabScanner struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type gitLabLocation struct {
+	File      string       `json:"file"`
+	StartLine int          `json:"start_line,omitempty"`
+	EndLine   int          `json:"end_line,omitempty"`
+	Commit    gitLabCommit `json:"commit"`
+}
+
+type gitLabCommit struct {
+	SHA string `json:"sha"`
+}
+
+type gitLabIdentifier struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type gitLabScan struct {
+	Analyzer  gitLabTool `json:"analyzer"`
+	Scanner   gitLabTool `json:"scanner"`
+	Type      string     `json:"type"`
+	StartTime string     `json:"start_time"`
+	EndTime   string     `json:"end_time"`
+	Status    string     `json:"status"`
+}
+
+type gitLabTool struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Version string       `json:"version"`
+	Vendor  gitLabVendor `json:"vendor"`
+}
+
+type gitLabVendor struct {
+	Name string `json:"name"`
+}
+
+// WriteGitLab writes the result as a GitLab secret detection report, shown
+// in the merge request widget when uploaded as artifacts:reports:secret_detection.
+func WriteGitLab(w io.Writer, result *Result) error {
+	tool := gitLabTool{
+		ID:      "entro-scan",
+		Name:    "Entro scan",
+		Version: "v2",
+		Vendor:  gitLabVendor{Name: "Entro"},
+	}
+
+	status := "success"
+	if result.Count(StatusError) > 0 {
+		status = "failure"
+	}
+
+	rep := gitLabReport{
+		Version:         gitLabSchemaVersion,
+		Vulnerabilities: make([]gitLabVulnerability, 0, len(result.Secrets)),
+		Scan: gitLabScan{
+			Analyzer:  tool,
+			Scanner:   tool,
+			Type:      "secret_detection",
+			StartTime: result.Started.UTC().Format(gitLabTimeFormat),
+			EndTime:   result.Finished.UTC().Format(gitLabTimeFormat),
+			Status:    status,
+		},
+	}
+
+	for _, s := range result.Secrets {
+		// Secrets not at a line of the head commit are located at their
+		// file only.
+		line, _ := result.headLine(s)
+
+		rep.Vulnerabilities = append(rep.Vulnerabilities, gitLabVulnerability{
+			ID:          uuid(s.Key()),
+			Category:    "secret_detection",
+			Name:        s.Origin,
+			Description: fmt.Sprintf("%s secret %s (%s)", s.Origin, s.Value, lifecycle(s)),
+			Severity:    gitLabSeverity(s.Severity),
+			Scanner:     gitLabScanner{ID: tool.ID, Name: tool.Name},
+			Location: gitLabLocation{
+				File:      s.File,
+				StartLine: line,
+				EndLine:   line,
+				Commit:    gitLabCommit{SHA: s.Last().Commit},
+			},
+			Identifiers: []gitLabIdentifier{
+				{Type: "entro_origin", Name: "Entro " + s.Origin, Value: s.Origin},
+			},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rep); err != nil {
+		return fmt.Errorf("can't encode GitLab report: %w", err)
+	}
+
+	return nil
+}
+
+func gitLabSeverity(s findings.Severity) string {
+	name := s.String()
+
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// uuid derives a stable UUID formatted identifier from key, so the same
+// secret keeps its identity across pipelines.
+func uuid(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
@@ -0,0 +1,94 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteGitLab(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteGitLab(&buf, testResult())
+	assert.NoError(t, err)
+
+	want := `{
+  "version": "15.0.7",
+  "vulnerabilities": [
+    {
+      "id": "6659463e-90f0-8c36-d94a-68ae5fd1eff4",
+      "category": "secret_detection",
+      "name": "GITHUB_API_TOKEN",
+      "description": "GITHUB_API_TOKEN secret ghp_BTqL****82UC7vz (introduced in ` + "`9006ae9`" + `, present at HEAD)",
+      "severity": "High",
+      "scanner": {
+        "id": "entro-scan",
+        "name": "Entro scan"
+      },
+      "location": {
+        "file": "config/app.yml",
+        "start_line": 5,
+        "end_line": 5,
+        "commit": {
+          "sha": "539533aab24270f6201fcdd5aa25f6c16662ee58"
+        }
+      },
+      "identifiers": [
+        {
+          "type": "entro_origin",
+          "name": "Entro GITHUB_API_TOKEN",
+          "value": "GITHUB_API_TOKEN"
+        }
+      ]
+    },
+    {
+      "id": "6fbafbc3-15eb-e3be-1111-5bc29d8a9ed1",
+      "category": "secret_detection",
+      "name": "GENERIC_PASSWORD",
+      "description": "GENERIC_PASSWORD secret hun****2 (removed in ` + "`539533a`" + `)",
+      "severity": "Info",
+      "scanner": {
+        "id": "entro-scan",
+        "name": "Entro scan"
+      },
+      "location": {
+        "file": "notes.md",
+        "commit": {
+          "sha": "539533aab24270f6201fcdd5aa25f6c16662ee58"
+        }
+      },
+      "identifiers": [
+        {
+          "type": "entro_origin",
+          "name": "Entro GENERIC_PASSWORD",
+          "value": "GENERIC_PASSWORD"
+        }
+      ]
+    }
+  ],
+  "scan": {
+    "analyzer": {
+      "id": "entro-scan",
+      "name": "Entro scan",
+      "version": "v2",
+      "vendor": {
+        "name": "Entro"
+      }
+    },
+    "scanner": {
+      "id": "entro-scan",
+      "name": "Entro scan",
+      "version": "v2",
+      "vendor": {
+        "name": "Entro"
+      }
+    },
+    "type": "secret_detection",
+    "start_time": "2026-10-18T12:00:00",
+    "end_time": "2026-10-18T12:00:03",
+    "status": "success"
+  }
+}
+`
+	assert.Equal(t, want, buf.String())
+}
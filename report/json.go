@@ -0,0 +1,19 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WriteJSON writes the result as an indented JSON document.
+func WriteJSON(w io.Writer, result *Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(result); err != nil {
+		return fmt.Errorf("can't encode JSON report: %w", err)
+	}
+
+	return nil
+}
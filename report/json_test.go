@@ -0,0 +1,25 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJSON(t *testing.T) {
+	result := testResult()
+
+	var buf bytes.Buffer
+	err := WriteJSON(&buf, result)
+	assert.NoError(t, err)
+
+	var got Result
+	err = json.Unmarshal(buf.Bytes(), &got)
+	assert.NoError(t, err)
+	assert.Equal(t, result, &got)
+
+	assert.Contains(t, buf.String(), `"severity": "high"`)
+	assert.Contains(t, buf.String(), `"side": "removed"`)
+}
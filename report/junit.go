@@ -0,0 +1,151 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/liminal-security/scan-action/findings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr,omitempty"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the result as JUnit XML with one test suite per commit
+// and one test case per scanned file. Secrets at or above the fail-on
+// severity are failures, the others are listed in the system output.
+func WriteJUnit(w io.Writer, result *Result) error {
+	suites := junitTestSuites{Name: "Entro secrets scan"}
+	if !result.Started.IsZero() && !result.Finished.IsZero() {
+		suites.Time = fmt.Sprintf("%.3f", result.Finished.Sub(result.Started).Seconds())
+	}
+
+	for _, c := range result.Commits {
+		suite := junitTestSuite{Name: "commit " + c.Hash}
+		if !result.Started.IsZero() {
+			suite.Timestamp = result.Started.UTC().Format("2006-01-02T15:04:05")
+		}
+
+		switch c.Status {
+		case StatusError:
+			suite.Errors++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				ClassName: c.Hash,
+				Name:      "scan",
+				Error:     &junitMessage{Message: c.Error, Type: "ScanError"},
+			})
+		case StatusSkipped:
+			suite.Skipped++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				ClassName: c.Hash,
+				Name:      "scan",
+				Skipped:   &junitMessage{Message: "no changed lines"},
+			})
+		default:
+			for _, file := range c.Files {
+				tc := junitCase(result, c.Hash, file)
+				if tc.Failure != nil {
+					suite.Failures++
+				}
+				suite.Cases = append(suite.Cases, tc)
+			}
+		}
+
+		suite.Tests = len(suite.Cases)
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("can't write JUnit report: %w", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return fmt.Errorf("can't encode JUnit report: %w", err)
+	}
+
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("can't write JUnit report: %w", err)
+	}
+
+	return nil
+}
+
+// junitCase collects the secrets found in file by commit.
+func junitCase(result *Result, commit string, file string) junitTestCase {
+	tc := junitTestCase{ClassName: commit, Name: file}
+
+	var failures, others []string
+	var failing []*findings.Secret
+
+	for _, s := range result.Secrets {
+		if s.File != file {
+			continue
+		}
+
+		for _, o := range s.Occurrences {
+			if o.Commit != commit {
+				continue
+			}
+
+			line := fmt.Sprintf("%s: %s %s at line %d (%s)", s.Origin, o.Side, s.Value, o.Line, s.Severity)
+			if s.Failing {
+				failures = append(failures, line)
+				failing = append(failing, s)
+			} else {
+				others = append(others, line)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		tc.Failure = &junitMessage{
+			Message: fmt.Sprintf("%d secret(s) found: %s %s", len(failures), failing[0].Origin, failing[0].Value),
+			Type:    failing[0].Origin,
+			Text:    strings.Join(failures, "\n"),
+		}
+	}
+	tc.SystemOut = strings.Join(others, "\n")
+
+	return tc
+}
@@ -0,0 +1,51 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	result := testResult()
+	result.Commits[0].Files = []string{"config/app.yml", "notes.md"}
+	result.Commits[1].Files = []string{"config/app.yml"}
+	result.Commits = append(result.Commits,
+		Commit{Hash: "cf79a0c4802ab43e6a247ecc1d68d0ba59998133", Status: StatusError, Error: "HTTP code 500: <oops>"},
+		Commit{Hash: "1a05c3eb00de25cf6cb10796dc569432e0a7a27f", Status: StatusSkipped},
+	)
+
+	var buf bytes.Buffer
+	err := WriteJUnit(&buf, result)
+	assert.NoError(t, err)
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="Entro secrets scan" tests="5" failures="2" errors="1" skipped="1" time="3.000">
+  <testsuite name="commit 539533aab24270f6201fcdd5aa25f6c16662ee58" tests="2" failures="1" errors="0" skipped="0" timestamp="2026-10-18T12:00:00">
+    <testcase classname="539533aab24270f6201fcdd5aa25f6c16662ee58" name="config/app.yml">
+      <failure message="1 secret(s) found: GITHUB_API_TOKEN ghp_BTqL****82UC7vz" type="GITHUB_API_TOKEN">GITHUB_API_TOKEN: added ghp_BTqL****82UC7vz at line 5 (high)</failure>
+    </testcase>
+    <testcase classname="539533aab24270f6201fcdd5aa25f6c16662ee58" name="notes.md">
+      <system-out>GENERIC_PASSWORD: removed hun****2 at line 1 (info)</system-out>
+    </testcase>
+  </testsuite>
+  <testsuite name="commit 9006ae9c5d2b99c774da25f7b91bd7e8457b2275" tests="1" failures="1" errors="0" skipped="0" timestamp="2026-10-18T12:00:00">
+    <testcase classname="9006ae9c5d2b99c774da25f7b91bd7e8457b2275" name="config/app.yml">
+      <failure message="1 secret(s) found: GITHUB_API_TOKEN ghp_BTqL****82UC7vz" type="GITHUB_API_TOKEN">GITHUB_API_TOKEN: added ghp_BTqL****82UC7vz at line 3 (high)</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="commit cf79a0c4802ab43e6a247ecc1d68d0ba59998133" tests="1" failures="0" errors="1" skipped="0" timestamp="2026-10-18T12:00:00">
+    <testcase classname="cf79a0c4802ab43e6a247ecc1d68d0ba59998133" name="scan">
+      <error message="HTTP code 500: &lt;oops&gt;" type="ScanError"></error>
+    </testcase>
+  </testsuite>
+  <testsuite name="commit 1a05c3eb00de25cf6cb10796dc569432e0a7a27f" tests="1" failures="0" errors="0" skipped="1" timestamp="2026-10-18T12:00:00">
+    <testcase classname="1a05c3eb00de25cf6cb10796dc569432e0a7a27f" name="scan">
+      <skipped message="no changed lines"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	assert.Equal(t, want, buf.String())
+}
@@ -0,0 +1,146 @@
+package report
+
+import (
+	"slices"
+	"time"
+
+	"github.com/liminal-security/scan-action/findings"
+	"github.com/liminal-security/scan-action/git"
+)
+
+// Status of a scanned commit.
+type Status string
+
+const (
+	StatusClean    Status = "clean"
+	StatusFindings Status = "findings"
+	StatusError    Status = "error"
+	// StatusSkipped is used for commits without changed lines.
+	StatusSkipped Status = "skipped"
+)
+
+// Commit is the scan outcome of a single commit.
+type Commit struct {
+	Hash string `json:"hash"`
+	// Parent is the commit the changes are relative to, empty if unknown.
+	Parent    string `json:"parent,omitempty"`
+	Status    Status `json:"status"`
+	RequestID string `json:"requestId,omitempty"`
+	Findings  int    `json:"findings"`
+	Error     string `json:"error,omitempty"`
+	// Files are the changed files of the commit.
+	Files []string `json:"files,omitempty"`
+}
+
+// Result is the outcome of a scan handed to the reporters.
+type Result struct {
+	// Commits are the scanned commits, newest first.
+	Commits []Commit           `json:"commits"`
+	Secrets []*findings.Secret `json:"secrets"`
+	// FailOn is the lowest severity failing the scan.
+	FailOn findings.Severity `json:"failOn"`
+	// VerifiedOnly means only secrets verified to be live fail the scan.
+	VerifiedOnly bool      `json:"verifiedOnly,omitempty"`
+	ExitCode     int       `json:"exitCode"`
+	Started      time.Time `json:"started,omitzero"`
+	Finished     time.Time `json:"finished,omitzero"`
+}
+
+// Hashes of the scanned commits, newest first.
+func (r *Result) Hashes() []string {
+	hashes := make([]string, 0, len(r.Commits))
+	for _, c := range r.Commits {
+		hashes = append(hashes, c.Hash)
+	}
+
+	return hashes
+}
+
+// parent returns the parent of the scanned commit hash, empty if unknown.
+func (r *Result) parent(hash string) string {
+	for _, c := range r.Commits {
+		if c.Hash == hash {
+			return c.Parent
+		}
+	}
+
+	return ""
+}
+
+// Count returns the number of commits with the status.
+func (r *Result) Count(status Status) int {
+	n := 0
+	for _, c := range r.Commits {
+		if c.Status == status {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Failing returns the secrets the policy marked as failing the scan.
+func (r *Result) Failing() []*findings.Secret {
+	var failing []*findings.Secret
+	for _, s := range r.Secrets {
+		if s.Failing {
+			failing = append(failing, s)
+		}
+	}
+
+	return failing
+}
+
+// headLine returns the line of a secret present at HEAD in the HEAD version
+// of its file. It is only known when no scanned commit changed the file
+// after the last one adding the secret.
+func (r *Result) headLine(s *findings.Secret) (int, bool) {
+	if !s.PresentAtHead {
+		return 0, false
+	}
+
+	var added findings.Occurrence
+	for _, o := range s.Occurrences {
+		if o.Side == git.SideAdded {
+			added = o
+		}
+	}
+	if added.Line == 0 {
+		return 0, false
+	}
+
+	for _, c := range r.Commits {
+		if c.Hash == added.Commit {
+			return added.Line, true
+		}
+		if slices.Contains(c.Files, s.File) {
+			return 0, false
+		}
+	}
+
+	return 0, false
+}
+
+// baseLine returns the line of a removed secret in the version of its file
+// before the scanned commits. It is only known when no scanned commit
+// changed the file before the first one removing the secret.
+func (r *Result) baseLine(s *findings.Secret) (int, bool) {
+	i := slices.IndexFunc(s.Occurrences, func(o findings.Occurrence) bool {
+		return o.Side == git.SideRemoved
+	})
+	if i < 0 || s.Occurrences[i].Line == 0 {
+		return 0, false
+	}
+	removed := s.Occurrences[i]
+
+	for _, c := range slices.Backward(r.Commits) {
+		if c.Hash == removed.Commit {
+			return removed.Line, true
+		}
+		if slices.Contains(c.Files, s.File) {
+			return 0, false
+		}
+	}
+
+	return 0, false
+}
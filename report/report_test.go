@@ -0,0 +1,53 @@
+package report
+
+import (
+	"time"
+
+	"github.com/liminal-security/scan-action/findings"
+	"github.com/liminal-security/scan-action/git"
+)
+
+const (
+	commit1 = "9006ae9c5d2b99c774da25f7b91bd7e8457b2275"
+	commit2 = "539533aab24270f6201fcdd5aa25f6c16662ee58"
+)
+
+func testResult() *Result {
+	return &Result{
+		Commits: []Commit{
+			{Hash: commit2, Status: StatusFindings, RequestID: "r2", Findings: 2},
+			{Hash: commit1, Status: StatusFindings, RequestID: "r1", Findings: 1},
+		},
+		Secrets: []*findings.Secret{
+			{
+				Fingerprint:   "0011223344556677",
+				Origin:        "GITHUB_API_TOKEN",
+				Value:         "ghp_BTqL****82UC7vz",
+				File:          "config/app.yml",
+				IntroducedIn:  commit1,
+				PresentAtHead: true,
+				Occurrences: []findings.Occurrence{
+					{Commit: commit1, Line: 3, Side: git.SideAdded},
+					{Commit: commit2, Line: 5, Side: git.SideAdded},
+				},
+				Severity: findings.SeverityHigh,
+				Failing:  true,
+			},
+			{
+				Fingerprint: "8899aabbccddeeff",
+				Origin:      "GENERIC_PASSWORD",
+				Value:       "hun****2",
+				File:        "notes.md",
+				RemovedIn:   commit2,
+				Occurrences: []findings.Occurrence{
+					{Commit: commit2, Line: 1, Side: git.SideRemoved},
+				},
+				Severity: findings.SeverityInfo,
+			},
+		},
+		FailOn:   findings.SeverityLow,
+		ExitCode: 2,
+		Started:  time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		Finished: time.Date(2026, 10, 18, 12, 0, 3, 0, time.UTC),
+	}
+}
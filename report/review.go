@@ -0,0 +1,252 @@
+package report
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/liminal-security/scan-action/findings"
+	"github.com/liminal-security/scan-action/git"
+	"github.com/liminal-security/scan-action/github"
+)
+
+// reviewMarker tags the review comments created by the scanner, so
+// re-runs find and update them instead of commenting again.
+var reviewMarker = regexp.MustCompile(`<!-- entro-scan:(\S+?)( resolved)? -->`)
+
+// Review posts findings as inline comments of a single pull request review.
+// Comments of previous runs, and the findings listed in the review bodies,
+// are updated, or marked resolved once the secret isn't found anymore.
+type Review struct {
+	Client      *github.Client
+	Owner       string
+	Repo        string
+	PullRequest *github.PullRequest
+}
+
+func (r *Review) Report(ctx context.Context, result *Result) error {
+	existing, err := r.Client.ListReviewComments(ctx, r.Owner, r.Repo, r.PullRequest.Number)
+	if err != nil {
+		return err
+	}
+
+	own := map[string]github.ReviewComment{}
+	resolved := map[string]bool{}
+	for _, c := range existing {
+		if m := reviewMarker.FindStringSubmatch(c.Body); m != nil {
+			own[m[1]] = c
+			resolved[m[1]] = m[2] != ""
+		}
+	}
+
+	// Findings listed in the body of a previous review aren't reported
+	// again.
+	reviews, err := r.Client.ListReviews(ctx, r.Owner, r.Repo, r.PullRequest.Number)
+	if err != nil {
+		return err
+	}
+	listed := map[string]bool{}
+	for _, review := range reviews {
+		for _, m := range reviewMarker.FindAllStringSubmatch(review.Body, -1) {
+			listed[m[1]] = true
+		}
+	}
+
+	review := &github.Review{
+		CommitID: r.PullRequest.Head.SHA,
+		Event:    "COMMENT",
+	}
+	current := map[string]github.ReviewComment{}
+
+	// Comments without a line in the diff are listed in the review body.
+	var outside []github.ReviewComment
+
+	for _, s := range result.Secrets {
+		comment, ok := inlineComment(result, s)
+		if !ok {
+			continue
+		}
+		id := markerID(s)
+		current[id] = comment
+
+		if prev, ok := own[id]; ok {
+			if prev.Body != comment.Body {
+				if err := r.Client.UpdateReviewComment(ctx, r.Owner, r.Repo, prev.ID, comment.Body); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+		if listed[id] {
+			continue
+		}
+
+		if comment.Line == 0 {
+			outside = append(outside, comment)
+		} else {
+			review.Comments = append(review.Comments, comment)
+		}
+	}
+
+	for id, c := range own {
+		if _, ok := current[id]; ok || resolved[id] {
+			continue
+		}
+
+		if err := r.Client.UpdateReviewComment(ctx, r.Owner, r.Repo, c.ID, resolvedBody(id)); err != nil {
+			return err
+		}
+	}
+
+	for _, prev := range reviews {
+		body := updateListing(prev.Body, current)
+		if body == prev.Body {
+			continue
+		}
+
+		if err := r.Client.UpdateReview(ctx, r.Owner, r.Repo, r.PullRequest.Number, prev.ID, body); err != nil {
+			return err
+		}
+	}
+
+	found := len(review.Comments) + len(outside)
+	if found == 0 {
+		return nil
+	}
+
+	if len(review.Comments) > 0 {
+		review.Body = reviewBody(found, outside)
+
+		_, err = r.Client.CreateReview(ctx, r.Owner, r.Repo, r.PullRequest.Number, review)
+		if !errors.Is(err, &github.APIError{Code: http.StatusUnprocessableEntity}) {
+			return err
+		}
+	}
+
+	// A line outside of the pull request diff fails the whole review,
+	// comment the others one by one and list the rest in the review body.
+	for _, comment := range review.Comments {
+		comment.CommitID = review.CommitID
+		_, err := r.Client.CreateReviewComment(ctx, r.Owner, r.Repo, r.PullRequest.Number, &comment)
+		switch {
+		case errors.Is(err, &github.APIError{Code: http.StatusUnprocessableEntity}):
+			comment.CommitID = ""
+			outside = append(outside, comment)
+		case err != nil:
+			return err
+		}
+	}
+	if len(outside) == 0 {
+		return nil
+	}
+
+	review.Body = reviewBody(found, outside)
+	review.Comments = nil
+	_, err = r.Client.CreateReview(ctx, r.Owner, r.Repo, r.PullRequest.Number, review)
+
+	return err
+}
+
+// inlineComment builds the comment of a secret. Only secrets present at
+// HEAD, or removed lines of the base, are part of the pull request diff.
+// Line is 0 when the line of the secret in the HEAD or base version of its
+// file isn't known.
+func inlineComment(result *Result, s *findings.Secret) (github.ReviewComment, bool) {
+	var (
+		side   git.Side
+		ghSide string
+		line   int
+	)
+
+	switch {
+	case s.PresentAtHead:
+		side, ghSide = git.SideAdded, "RIGHT"
+		line, _ = result.headLine(s)
+	case s.IntroducedIn == "":
+		side, ghSide = git.SideRemoved, "LEFT"
+		line, _ = result.baseLine(s)
+	default:
+		return github.ReviewComment{}, false
+	}
+
+	var body string
+	if side == git.SideRemoved {
+		body = fmt.Sprintf("**Entro secrets scan**: removed %s secret %s, it is still in the git history, consider rotating it.",
+			s.Origin, code(s.Value))
+	} else {
+		body = fmt.Sprintf("**Entro secrets scan**: found `%s` %s secret %s (%s). Rotate it and remove it from the git history.",
+			s.Severity, s.Origin, code(s.Value), lifecycle(s))
+	}
+
+	return github.ReviewComment{
+		Path: s.File,
+		Line: line,
+		Side: ghSide,
+		Body: fmt.Sprintf("%s\n\n<!-- entro-scan:%s -->", body, markerID(s)),
+	}, true
+}
+
+// markerID identifies the secret in the comment marker.
+func markerID(s *findings.Secret) string {
+	sum := sha256.Sum256([]byte(s.Key()))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+func resolvedBody(id string) string {
+	return fmt.Sprintf(":white_check_mark: **Entro secrets scan**: this secret isn't found anymore.\n\n<!-- entro-scan:%s resolved -->", id)
+}
+
+// reviewBody summarizes the review, listing the comments that can't be
+// placed inline along with their markers.
+func reviewBody(found int, listed []github.ReviewComment) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "**Entro secrets scan** found %d new secret(s) in this pull request.", found)
+	if len(listed) > 0 {
+		b.WriteString(" These can't be commented on their line:\n\n")
+	}
+	for _, c := range listed {
+		b.WriteString(listEntry(c) + "\n")
+	}
+
+	return b.String()
+}
+
+// listEntry lists the comment in a review body, along with its marker.
+func listEntry(c github.ReviewComment) string {
+	body := strings.TrimSpace(reviewMarker.ReplaceAllString(c.Body, ""))
+	location := c.Path
+	if c.Line > 0 {
+		location = fmt.Sprintf("%s:%d", c.Path, c.Line)
+	}
+
+	return fmt.Sprintf("- `%s`: %s %s", location, body, reviewMarker.FindString(c.Body))
+}
+
+// updateListing rewrites the entries listed in a review body: those of
+// current secrets get their current comment, the others are resolved.
+func updateListing(body string, current map[string]github.ReviewComment) string {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		m := reviewMarker.FindStringSubmatch(line)
+		if m == nil || !strings.HasPrefix(line, "- `") {
+			continue
+		}
+
+		if c, ok := current[m[1]]; ok {
+			lines[i] = listEntry(c)
+		} else if m[2] == "" {
+			location, _, _ := strings.Cut(strings.TrimPrefix(line, "- `"), "`")
+			lines[i] = fmt.Sprintf("- ~~`%s`~~: :white_check_mark: this secret isn't found anymore. <!-- entro-scan:%s resolved -->", location, m[1])
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,138 @@
+package report
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liminal-security/scan-action/github"
+)
+
+func testReview(url string) *Review {
+	pr := &github.PullRequest{Number: 7}
+	pr.Head.SHA = commit2
+
+	return &Review{
+		Client:      github.NewClient(url, "gh-token"),
+		Owner:       "liminal-security",
+		Repo:        "scan-action-test",
+		PullRequest: pr,
+	}
+}
+
+func TestReview(t *testing.T) {
+	fake, svr := newFakeGitHub(t)
+	review := testReview(svr.URL)
+	ctx := context.Background()
+
+	result := testResult()
+	err := review.Report(ctx, result)
+	assert.NoError(t, err)
+
+	assert.Len(t, fake.reviews, 1)
+	assert.Equal(t, commit2, fake.reviews[0]["commit_id"])
+	assert.Equal(t, []string{
+		"**Entro secrets scan**: found `high` GITHUB_API_TOKEN secret `ghp_BTqL****82UC7vz` (introduced in `9006ae9`, present at HEAD). Rotate it and remove it from the git history.",
+		"**Entro secrets scan**: removed GENERIC_PASSWORD secret `hun****2`, it is still in the git history, consider rotating it.",
+	}, fake.commentBodies())
+
+	comments := fake.reviews[0]["comments"].([]any)
+	assert.Equal(t, map[string]any{
+		"id":   int64(1),
+		"path": "config/app.yml",
+		"line": float64(5),
+		"side": "RIGHT",
+		"body": comments[0].(map[string]any)["body"],
+	}, comments[0])
+	assert.Equal(t, "LEFT", comments[1].(map[string]any)["side"])
+
+	// Re-running with the same findings doesn't comment again.
+	err = review.Report(ctx, result)
+	assert.NoError(t, err)
+	assert.Len(t, fake.reviews, 1)
+
+	// Once a secret is gone its comment is resolved.
+	result.Secrets = result.Secrets[:1]
+	err = review.Report(ctx, result)
+	assert.NoError(t, err)
+	assert.Len(t, fake.reviews, 1)
+	assert.Equal(t, []string{
+		"**Entro secrets scan**: found `high` GITHUB_API_TOKEN secret `ghp_BTqL****82UC7vz` (introduced in `9006ae9`, present at HEAD). Rotate it and remove it from the git history.",
+		":white_check_mark: **Entro secrets scan**: this secret isn't found anymore.",
+	}, fake.commentBodies())
+}
+
+func TestReviewOutsideOfDiff(t *testing.T) {
+	fake, svr := newFakeGitHub(t)
+	fake.outsideDiff = "notes.md"
+	review := testReview(svr.URL)
+	ctx := context.Background()
+
+	err := review.Report(ctx, testResult())
+	assert.NoError(t, err)
+
+	// The comment inside the diff is posted on its own.
+	assert.Equal(t, []string{
+		"**Entro secrets scan**: found `high` GITHUB_API_TOKEN secret `ghp_BTqL****82UC7vz` (introduced in `9006ae9`, present at HEAD). Rotate it and remove it from the git history.",
+	}, fake.commentBodies())
+	assert.Equal(t, commit2, fake.comments[1]["commit_id"])
+
+	assert.Len(t, fake.reviews, 1)
+	assert.Nil(t, fake.reviews[0]["comments"])
+	body := fake.reviews[0]["body"].(string)
+	assert.Contains(t, body, "found 2 new secret(s)")
+	assert.Contains(t, body, "- `notes.md:1`: **Entro secrets scan**: removed GENERIC_PASSWORD secret `hun****2`")
+	assert.NotContains(t, body, "config/app.yml")
+	assert.Regexp(t, reviewMarker, body)
+
+	// Re-running finds both the comment and the listed secret.
+	err = review.Report(ctx, testResult())
+	assert.NoError(t, err)
+	assert.Len(t, fake.reviews, 1)
+	assert.Len(t, fake.commentBodies(), 1)
+	assert.Zero(t, fake.reviewUpdates)
+
+	// A listed secret on another line is updated in place.
+	result := testResult()
+	result.Secrets[1].Occurrences[0].Line = 2
+	err = review.Report(ctx, result)
+	assert.NoError(t, err)
+	assert.Len(t, fake.reviews, 1)
+	assert.Equal(t, 1, fake.reviewUpdates)
+	body = fake.reviews[0]["body"].(string)
+	assert.Contains(t, body, "- `notes.md:2`: **Entro secrets scan**: removed GENERIC_PASSWORD secret `hun****2`")
+	assert.NotContains(t, body, "notes.md:1")
+
+	// Once it is gone its entry is resolved, and left alone afterwards.
+	result.Secrets = result.Secrets[:1]
+	for range 2 {
+		err = review.Report(ctx, result)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, fake.reviewUpdates)
+	body = fake.reviews[0]["body"].(string)
+	assert.Contains(t, body, "- ~~`notes.md:2`~~: :white_check_mark: this secret isn't found anymore.")
+	assert.NotContains(t, body, "hun****2")
+	assert.Len(t, fake.commentBodies(), 1)
+}
+
+func TestReviewMovedLines(t *testing.T) {
+	fake, svr := newFakeGitHub(t)
+
+	// The token was added by commit1 and its file changed by commit2, its
+	// line at the head isn't known anymore.
+	result := testResult()
+	result.Commits[0].Files = []string{"config/app.yml"}
+	result.Commits[1].Files = []string{"config/app.yml"}
+	result.Secrets[0].Occurrences = result.Secrets[0].Occurrences[:1]
+
+	err := testReview(svr.URL).Report(context.Background(), result)
+	assert.NoError(t, err)
+
+	assert.Len(t, fake.reviews, 1)
+	comments := fake.reviews[0]["comments"].([]any)
+	assert.Len(t, comments, 1)
+	assert.Equal(t, "notes.md", comments[0].(map[string]any)["path"])
+	assert.Contains(t, fake.reviews[0]["body"], "- `config/app.yml`: **Entro secrets scan**: found `high` GITHUB_API_TOKEN")
+}
@@ -0,0 +1,156 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/liminal-security/scan-action/findings"
+	"github.com/liminal-security/scan-action/git"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	RelatedLocations    []sarifLocation   `json:"relatedLocations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	ID               *int                  `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// WriteSARIF writes the result as a SARIF 2.1.0 log with one result per
+// secret, one rule per origin. The result is located at the HEAD line of
+// the secret when known, at its file otherwise, and every occurrence is a
+// related location naming its commit.
+func WriteSARIF(w io.Writer, result *Result) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "Entro secrets scan",
+			InformationURI: "https://entro.security",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := map[string]bool{}
+	for _, s := range result.Secrets {
+		if !rules[s.Origin] {
+			rules[s.Origin] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               s.Origin,
+				ShortDescription: sarifMessage{Text: s.Origin + " secret"},
+			})
+		}
+
+		run.Results = append(run.Results, sarifSecret(result, s))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}}); err != nil {
+		return fmt.Errorf("can't encode SARIF report: %w", err)
+	}
+
+	return nil
+}
+
+// sarifSecret converts the secret to a SARIF result.
+func sarifSecret(result *Result, s *findings.Secret) sarifResult {
+	line, _ := result.headLine(s)
+
+	r := sarifResult{
+		RuleID:              s.Origin,
+		Level:               sarifLevel(s),
+		Message:             sarifMessage{Text: fmt.Sprintf("%s secret `%s` (%s)", s.Origin, s.Value, lifecycle(s))},
+		Locations:           []sarifLocation{{PhysicalLocation: sarifPhysical(s.File, line)}},
+		PartialFingerprints: map[string]string{"entroFingerprint/v2": s.Fingerprint},
+	}
+
+	for i, o := range s.Occurrences {
+		id := i
+		verb := "added"
+		if o.Side == git.SideRemoved {
+			verb = "removed"
+		}
+		r.RelatedLocations = append(r.RelatedLocations, sarifLocation{
+			ID:               &id,
+			PhysicalLocation: sarifPhysical(s.File, o.Line),
+			Message:          &sarifMessage{Text: fmt.Sprintf("%s in commit %s", verb, o.Commit)},
+		})
+	}
+
+	return r
+}
+
+func sarifPhysical(file string, line int) sarifPhysicalLocation {
+	loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: file}}
+	if line > 0 {
+		loc.Region = &sarifRegion{StartLine: line}
+	}
+
+	return loc
+}
+
+// sarifLevel is error for secrets failing the scan, note for info ones and
+// warning for the others.
+func sarifLevel(s *findings.Secret) string {
+	switch {
+	case s.Failing:
+		return "error"
+	case s.Severity == findings.SeverityInfo:
+		return "note"
+	default:
+		return "warning"
+	}
+}
@@ -0,0 +1,63 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	result := testResult()
+	result.Commits[0].Files = []string{"config/app.yml", "notes.md"}
+	result.Commits[1].Files = []string{"config/app.yml"}
+
+	var buf bytes.Buffer
+	err := WriteSARIF(&buf, result)
+	assert.NoError(t, err)
+
+	var log sarifLog
+	err = json.Unmarshal(buf.Bytes(), &log)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.1.0", log.Version)
+	if !assert.Len(t, log.Runs, 1) {
+		return
+	}
+	run := log.Runs[0]
+
+	assert.Equal(t, []sarifRule{
+		{ID: "GITHUB_API_TOKEN", ShortDescription: sarifMessage{Text: "GITHUB_API_TOKEN secret"}},
+		{ID: "GENERIC_PASSWORD", ShortDescription: sarifMessage{Text: "GENERIC_PASSWORD secret"}},
+	}, run.Tool.Driver.Rules)
+	if !assert.Len(t, run.Results, 2) {
+		return
+	}
+
+	token := run.Results[0]
+	assert.Equal(t, "error", token.Level)
+	assert.Equal(t, "GITHUB_API_TOKEN secret `ghp_BTqL****82UC7vz` (introduced in `9006ae9`, present at HEAD)", token.Message.Text)
+	assert.Equal(t, []sarifLocation{{PhysicalLocation: sarifPhysical("config/app.yml", 5)}}, token.Locations)
+	assert.Equal(t, map[string]string{"entroFingerprint/v2": "0011223344556677"}, token.PartialFingerprints)
+
+	// Every occurrence is kept.
+	first, second := 0, 1
+	assert.Equal(t, []sarifLocation{
+		{ID: &first, PhysicalLocation: sarifPhysical("config/app.yml", 3), Message: &sarifMessage{Text: "added in commit " + commit1}},
+		{ID: &second, PhysicalLocation: sarifPhysical("config/app.yml", 5), Message: &sarifMessage{Text: "added in commit " + commit2}},
+	}, token.RelatedLocations)
+
+	password := run.Results[1]
+	assert.Equal(t, "note", password.Level)
+	assert.Equal(t, []sarifLocation{{PhysicalLocation: sarifPhysical("notes.md", 0)}}, password.Locations)
+	assert.Equal(t, "removed in commit "+commit2, password.RelatedLocations[0].Message.Text)
+}
+
+func TestWriteSARIFClean(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteSARIF(&buf, &Result{})
+	assert.NoError(t, err)
+
+	assert.Contains(t, buf.String(), `"results": []`)
+	assert.Contains(t, buf.String(), `"rules": []`)
+}
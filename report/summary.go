@@ -0,0 +1,167 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/liminal-security/scan-action/findings"
+	"github.com/liminal-security/scan-action/git"
+)
+
+// WriteSummary writes the result as GitHub flavored Markdown, suitable for
+// GITHUB_STEP_SUMMARY. File locations link to repoURL when it is set.
+func WriteSummary(w io.Writer, result *Result, repoURL string) error {
+	var b strings.Builder
+
+	b.WriteString("## Entro secrets scan\n\n")
+
+	failing := len(result.Failing())
+	if failing > 0 {
+		fmt.Fprintf(&b, ":x: Found %d secret(s) at or above `%s`\n\n", failing, result.FailOn)
+	} else {
+		fmt.Fprintf(&b, ":white_check_mark: No secrets at or above `%s`\n\n", result.FailOn)
+	}
+
+	b.WriteString("| Commits | Clean | With findings | Errored | Skipped |\n")
+	b.WriteString("|---------|-------|---------------|---------|---------|\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %d | %d |\n\n",
+		len(result.Commits),
+		result.Count(StatusClean),
+		result.Count(StatusFindings),
+		result.Count(StatusError),
+		result.Count(StatusSkipped),
+	)
+
+	if len(result.Secrets) > 0 {
+		b.WriteString("### Findings\n\n")
+		b.WriteString("| Severity | Origin | Location | Value | Lifecycle |\n")
+		b.WriteString("|----------|--------|----------|-------|-----------|\n")
+
+		for _, s := range result.Secrets {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+				s.Severity,
+				escape(s.Origin),
+				location(result, repoURL, s),
+				code(s.Value),
+				lifecycle(s),
+			)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(result.Commits) > 0 {
+		b.WriteString("### Commits\n\n")
+		b.WriteString("| Commit | Status | Findings | Request ID |\n")
+		b.WriteString("|--------|--------|----------|------------|\n")
+
+		for _, c := range result.Commits {
+			status := string(c.Status)
+			if c.Error != "" {
+				status += ": " + escape(c.Error)
+			}
+
+			requestID := ""
+			if c.RequestID != "" {
+				requestID = code(c.RequestID)
+			}
+
+			fmt.Fprintf(&b, "| %s | %s | %d | %s |\n", code(short(c.Hash)), status, c.Findings, requestID)
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("can't write summary: %w", err)
+	}
+
+	return nil
+}
+
+// location links to the line of the last occurrence of the secret. Removed
+// lines are in the blob of the parent of the commit removing them.
+func location(result *Result, repoURL string, s *findings.Secret) string {
+	last := s.Last()
+
+	text := s.File
+	if last.Line > 0 {
+		text = fmt.Sprintf("%s:%d", s.File, last.Line)
+	}
+	text = escape(text)
+
+	if repoURL == "" || last.Commit == "" {
+		return text
+	}
+
+	blob := last.Commit
+	if last.Side == git.SideRemoved {
+		blob = result.parent(last.Commit)
+		if blob == "" {
+			return fmt.Sprintf("[%s](%s/commit/%s)", text, repoURL, last.Commit)
+		}
+	}
+
+	segments := strings.Split(s.File, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+
+	link := fmt.Sprintf("%s/blob/%s/%s", repoURL, blob, strings.Join(segments, "/"))
+	if last.Line > 0 {
+		link += fmt.Sprintf("#L%d", last.Line)
+	}
+
+	return fmt.Sprintf("[%s](%s)", text, link)
+}
+
+func lifecycle(s *findings.Secret) string {
+	var parts []string
+
+	if s.IntroducedIn != "" {
+		parts = append(parts, "introduced in "+code(short(s.IntroducedIn)))
+	}
+	if s.RemovedIn != "" {
+		parts = append(parts, "removed in "+code(short(s.RemovedIn)))
+	}
+	if s.PresentAtHead {
+		parts = append(parts, "present at HEAD")
+	}
+	if v := verification(s); v != "" {
+		parts = append(parts, v)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// verification describes the verification of the secret, "" if it
+// wasn't verified.
+func verification(s *findings.Secret) string {
+	switch s.Verification {
+	case findings.VerificationVerified:
+		return "verified live"
+	case findings.VerificationInvalid:
+		return "verified invalid"
+	case findings.VerificationUnknown:
+		return "unverified"
+	default:
+		return ""
+	}
+}
+
+func short(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+
+	return hash
+}
+
+func escape(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+func code(s string) string {
+	return "`" + escape(strings.ReplaceAll(s, "`", "'")) + "`"
+}
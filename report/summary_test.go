@@ -0,0 +1,62 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSummary(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteSummary(&buf, testResult(), "https://github.com/liminal-security/scan-action-test")
+	assert.NoError(t, err)
+
+	want := "## Entro secrets scan\n\n" +
+		":x: Found 1 secret(s) at or above `low`\n\n" +
+		"| Commits | Clean | With findings | Errored | Skipped |\n" +
+		"|---------|-------|---------------|---------|---------|\n" +
+		"| 2 | 0 | 2 | 0 | 0 |\n\n" +
+		"### Findings\n\n" +
+		"| Severity | Origin | Location | Value | Lifecycle |\n" +
+		"|----------|--------|----------|-------|-----------|\n" +
+		"| high | GITHUB_API_TOKEN | [config/app.yml:5](https://github.com/liminal-security/scan-action-test/blob/539533aab24270f6201fcdd5aa25f6c16662ee58/config/app.yml#L5) | `ghp_BTqL****82UC7vz` | introduced in `9006ae9`, present at HEAD |\n" +
+		"| info | GENERIC_PASSWORD | [notes.md:1](https://github.com/liminal-security/scan-action-test/commit/539533aab24270f6201fcdd5aa25f6c16662ee58) | `hun****2` | removed in `539533a` |\n\n" +
+		"### Commits\n\n" +
+		"| Commit | Status | Findings | Request ID |\n" +
+		"|--------|--------|----------|------------|\n" +
+		"| `539533a` | findings | 2 | `r2` |\n" +
+		"| `9006ae9` | findings | 1 | `r1` |\n"
+
+	assert.Equal(t, want, buf.String())
+}
+
+func TestWriteSummaryClean(t *testing.T) {
+	result := &Result{
+		Commits: []Commit{
+			{Hash: commit2, Status: StatusError, Error: "HTTP code 500: a | b"},
+			{Hash: commit1, Status: StatusSkipped},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteSummary(&buf, result, "")
+	assert.NoError(t, err)
+
+	assert.Contains(t, buf.String(), ":white_check_mark: No secrets at or above `info`")
+	assert.Contains(t, buf.String(), "| 2 | 0 | 0 | 1 | 1 |")
+	assert.Contains(t, buf.String(), "| `539533a` | error: HTTP code 500: a \\| b | 0 |  |")
+	assert.NotContains(t, buf.String(), "### Findings")
+}
+
+func TestWriteSummaryRemovedAtParent(t *testing.T) {
+	result := testResult()
+	result.Commits[0].Parent = commit1
+
+	var buf bytes.Buffer
+	err := WriteSummary(&buf, result, "https://github.com/liminal-security/scan-action-test")
+	assert.NoError(t, err)
+
+	// The removed line is only in the blob of the parent.
+	assert.Contains(t, buf.String(), "[notes.md:1](https://github.com/liminal-security/scan-action-test/blob/9006ae9c5d2b99c774da25f7b91bd7e8457b2275/notes.md#L1)")
+}
@@ -0,0 +1,266 @@
+package scan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/liminal-security/scan-action/entro"
+	"github.com/liminal-security/scan-action/findings"
+	"github.com/liminal-security/scan-action/logging"
+)
+
+// Cache is an Engine serving the responses of already scanned payloads
+// from Dir, e.g. a directory restored by actions/cache. Only successful
+// responses are cached. Requests with provenance always reach Engine, the
+// platform records their findings.
+type Cache struct {
+	Engine Engine
+	Dir    string
+	// Version identifies the engine, its rules and settings, responses
+	// cached with another version aren't used. The API doesn't report the
+	// version of its rules, MaxAge bounds how long responses of outdated
+	// rules are served.
+	Version string
+	// MaxAge expires the cached responses, 0 keeps them forever.
+	MaxAge time.Duration
+	Logger *slog.Logger
+}
+
+// errExpired is returned for cached responses older than MaxAge.
+var errExpired = errors.New("cached scan response expired")
+
+func (c *Cache) Scan(ctx context.Context, scanReq *entro.ScanReq) (*entro.ScanResp, error) {
+	logger := logging.OrDiscard(c.Logger)
+	path := c.path(scanReq)
+
+	if scanReq.Provenance == nil {
+		resp, err := c.read(path, scanReq.Data)
+		if err == nil {
+			logger.Debug("serving scan response from cache", "path", path, "request_id", resp.RequestID)
+
+			return resp, nil
+		}
+		logCacheMiss(logger, path, err)
+	}
+
+	resp, err := c.Engine.Scan(ctx, scanReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeCached(path, scanReq.Data, resp); err != nil {
+		logger.Warn("can't cache scan response", "error", err)
+	}
+
+	return resp, nil
+}
+
+// ScanBatch serves the cached responses and scans the other requests
+// with Engine, in batches when it is a BatchEngine.
+func (c *Cache) ScanBatch(ctx context.Context, scanReqs []*entro.ScanReq) []entro.BatchResult {
+	logger := logging.OrDiscard(c.Logger)
+	results := make([]entro.BatchResult, len(scanReqs))
+
+	var missed []int
+	for i, scanReq := range scanReqs {
+		if scanReq.Provenance == nil {
+			path := c.path(scanReq)
+			resp, err := c.read(path, scanReq.Data)
+			if err == nil {
+				logger.Debug("serving scan response from cache", "path", path, "request_id", resp.RequestID)
+				results[i].Resp = resp
+
+				continue
+			}
+			logCacheMiss(logger, path, err)
+		}
+		missed = append(missed, i)
+	}
+	if len(missed) == 0 {
+		return results
+	}
+
+	pending := make([]*entro.ScanReq, len(missed))
+	for j, i := range missed {
+		pending[j] = scanReqs[i]
+	}
+
+	var scanned []entro.BatchResult
+	if batchEngine, ok := c.Engine.(BatchEngine); ok {
+		scanned = batchEngine.ScanBatch(ctx, pending)
+	} else {
+		scanned = make([]entro.BatchResult, len(pending))
+		for j, scanReq := range pending {
+			scanned[j].Resp, scanned[j].Err = c.Engine.Scan(ctx, scanReq)
+		}
+	}
+
+	for j, i := range missed {
+		results[i] = scanned[j]
+		if scanned[j].Err != nil {
+			continue
+		}
+		if err := writeCached(c.path(pending[j]), pending[j].Data, scanned[j].Resp); err != nil {
+			logger.Warn("can't cache scan response", "error", err)
+		}
+	}
+
+	return results
+}
+
+// path is content addressed, like the git object store.
+func (c *Cache) path(scanReq *entro.ScanReq) string {
+	sum := sha256.Sum256([]byte(c.Version + "\x00" + scanReq.Data))
+	key := hex.EncodeToString(sum[:])
+
+	return filepath.Join(c.Dir, key[:2], key[2:]+".json")
+}
+
+// read reads the cached response to the scanned data unless it expired.
+func (c *Cache) read(path string, scanned string) (*entro.ScanResp, error) {
+	if c.MaxAge > 0 {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if time.Since(info.ModTime()) > c.MaxAge {
+			return nil, errExpired
+		}
+	}
+
+	return readCached(path, scanned)
+}
+
+func logCacheMiss(logger *slog.Logger, path string, err error) {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case errors.Is(err, errExpired):
+		logger.Debug("cached scan response expired", "path", path)
+	default:
+		logger.Warn("ignoring cached scan response", "error", err)
+	}
+}
+
+// cachedResp is a ScanResp as cached. Secrets are never written to the
+// cache: values found verbatim in the scanned data are masked, and sliced
+// back out of the data, which the cache is keyed by, when read.
+type cachedResp struct {
+	RequestID  string         `json:"requestId"`
+	TotalCount int            `json:"totalCount"`
+	Results    []cachedResult `json:"results"`
+}
+
+type cachedResult struct {
+	entro.ScanResult
+	// Offset and Length locate a masked value in the scanned data, Length
+	// is 0 for values kept as returned.
+	Offset int `json:"offset,omitempty"`
+	Length int `json:"length,omitempty"`
+}
+
+// readCached reads the response to the scanned data.
+func readCached(path string, scanned string) (*entro.ScanResp, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cached cachedResp
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, fmt.Errorf("can't decode %s: %w", path, err)
+	}
+
+	resp := &entro.ScanResp{
+		RequestID:  cached.RequestID,
+		TotalCount: cached.TotalCount,
+		Results:    make([]entro.ScanResult, 0, len(cached.Results)),
+	}
+	for _, res := range cached.Results {
+		if res.Length > 0 {
+			if res.Offset < 0 || res.Offset+res.Length > len(scanned) {
+				return nil, fmt.Errorf("can't recover the %s value of line %d from %s", res.Origin, res.Line, path)
+			}
+			res.Value = scanned[res.Offset : res.Offset+res.Length]
+		}
+		resp.Results = append(resp.Results, res.ScanResult)
+	}
+
+	return resp, nil
+}
+
+// writeCached writes through a temporary file, so parallel runs sharing the
+// directory never read a partial response.
+func writeCached(path string, scanned string, resp *entro.ScanResp) error {
+	cached := cachedResp{RequestID: resp.RequestID, TotalCount: resp.TotalCount, Results: []cachedResult{}}
+	for _, res := range resp.Results {
+		result := cachedResult{ScanResult: res}
+		if offset := valueOffset(scanned, res); offset >= 0 {
+			result.Offset, result.Length = offset, len(res.Value)
+			result.Value = findings.Mask(res.Value)
+		}
+		cached.Results = append(cached.Results, result)
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return fmt.Errorf("can't encode scan response: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("can't create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("can't create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("can't write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("can't write cache file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("can't write cache file: %w", err)
+	}
+
+	return nil
+}
+
+// valueOffset returns the offset of the value of res in the scanned data,
+// preferring the first one from its line on, or -1 if it isn't there.
+func valueOffset(scanned string, res entro.ScanResult) int {
+	if res.Value == "" {
+		return -1
+	}
+
+	start := 0
+	for line := 1; line < res.Line && start >= 0; line++ {
+		next := strings.IndexByte(scanned[start:], '\n')
+		if next < 0 {
+			start = -1
+			break
+		}
+		start += next + 1
+	}
+	if start >= 0 {
+		if i := strings.Index(scanned[start:], res.Value); i >= 0 {
+			return start + i
+		}
+	}
+
+	return strings.Index(scanned, res.Value)
+}
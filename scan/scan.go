@@ -0,0 +1,486 @@
+// Package scan runs secret scans of git commits, the scan-action binary is
+// a thin wrapper around it.
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/liminal-security/scan-action/entro"
+	"github.com/liminal-security/scan-action/findings"
+	"github.com/liminal-security/scan-action/git"
+	"github.com/liminal-security/scan-action/logging"
+	"github.com/liminal-security/scan-action/policy"
+	"github.com/liminal-security/scan-action/redact"
+	"github.com/liminal-security/scan-action/report"
+	"github.com/liminal-security/scan-action/verify"
+)
+
+// prefetch is the number of commits diffed ahead of the scanned one.
+const prefetch = 2
+
+// ErrNoCommits is returned when the source has no commits, e.g. the
+// checkout is too shallow.
+var ErrNoCommits = errors.New("no commits to scan")
+
+// Engine finds secrets in a commit payload, *entro.Client is one.
+type Engine interface {
+	Scan(ctx context.Context, scanReq *entro.ScanReq) (*entro.ScanResp, error)
+}
+
+// BatchEngine is an Engine scanning several payloads at once, the results
+// are in the order of the requests. *entro.Client and *Cache are ones.
+type BatchEngine interface {
+	Engine
+	ScanBatch(ctx context.Context, scanReqs []*entro.ScanReq) []entro.BatchResult
+}
+
+// Verifier checks whether the secret found with origin is live,
+// *verify.Verifier is one.
+type Verifier interface {
+	Verify(ctx context.Context, origin, secret string) (findings.Verification, error)
+}
+
+// Provenance describes the scanned repository, the commit, author and
+// files are added per commit.
+type Provenance struct {
+	// Repository is the web URL of the repository.
+	Repository  string
+	PullRequest int
+}
+
+// Filter drops the findings it returns false for, e.g. allowlisted ones.
+type Filter func(f findings.Finding) bool
+
+// Reporter publishes the result of a scan, *report.Review and
+// *report.Checks are reporters.
+type Reporter interface {
+	Report(ctx context.Context, result *report.Result) error
+}
+
+// ReporterFunc adapts a func to a Reporter.
+type ReporterFunc func(ctx context.Context, result *report.Result) error
+
+func (f ReporterFunc) Report(ctx context.Context, result *report.Result) error {
+	return f(ctx, result)
+}
+
+// File returns a reporter writing the result to path with write, e.g.
+// report.WriteJSON.
+func File(path string, write func(io.Writer, *report.Result) error) Reporter {
+	return ReporterFunc(func(_ context.Context, result *report.Result) error {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("can't create %s: %w", path, err)
+		}
+		defer file.Close()
+
+		return write(file, result)
+	})
+}
+
+// Scanner scans the commits of Source with Engine. Only Source and Engine
+// are required.
+type Scanner struct {
+	Source Source
+	Engine Engine
+	// Filters are applied to every finding before tracking its lifecycle.
+	Filters []Filter
+	// Policy defaults to the default rules failing on low.
+	Policy *policy.Policy
+	// Reporters are called in order once the scan is done.
+	Reporters []Reporter
+	// FailOnError stops the scan at the first commit the engine fails on,
+	// otherwise the commit is reported with StatusError.
+	FailOnError bool
+	// Redactor masks finding values, it defaults to
+	// redact.DefaultVisible characters.
+	Redactor *redact.Redactor
+	// Verifier, when set, verifies every found secret once.
+	Verifier Verifier
+	// Provenance, when set, is sent with every commit so the platform
+	// records where the findings come from.
+	Provenance *Provenance
+	// Batch is the number of commits scanned together when Engine is a
+	// BatchEngine, 0 or 1 scans them one by one.
+	Batch int
+	// Group wraps the logs of every scanned commit, see logging.Group.
+	Group  func(name string) (end func())
+	Logger *slog.Logger
+}
+
+// Run scans the commits and reports the result. The result is returned
+// along with reporter errors, and up to the failing commit when
+// FailOnError stops the scan.
+func (s *Scanner) Run(ctx context.Context) (*report.Result, error) {
+	logger := logging.OrDiscard(s.Logger)
+	scanPolicy := s.Policy
+	if scanPolicy == nil {
+		scanPolicy = policy.New(nil, findings.SeverityLow)
+	}
+	redactor := s.Redactor
+	if redactor == nil {
+		redactor = redact.New(redact.DefaultVisible, nil)
+	}
+
+	result := &report.Result{
+		FailOn:       scanPolicy.FailOn,
+		VerifiedOnly: scanPolicy.VerifiedOnly,
+		Started:      time.Now(),
+	}
+
+	// Diff the next commits while scanning one, up to prefetch of them.
+	ctx, cancel := context.WithCancel(ctx)
+	commits := make(chan git.Commit, prefetch)
+	walked := make(chan error, 1)
+	// stop cancels the walk and waits for it to end.
+	stop := func() {
+		cancel()
+		for range commits {
+		}
+	}
+	defer stop()
+
+	go func() {
+		defer close(commits)
+
+		walked <- s.Source.Walk(ctx, func(commit git.Commit) error {
+			select {
+			case commits <- commit:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	var found []findings.Finding
+	scanned := &scannedFiles{
+		findings: map[git.FileRef][]findings.Finding{},
+		failed:   map[string]error{},
+		verified: map[string]findings.Verification{},
+	}
+	for {
+		window := receive(commits, s.Batch)
+		if len(window) == 0 {
+			break
+		}
+
+		// Commits are still handled one by one, in order, so duplicates of
+		// an earlier commit of the window fan out its findings.
+		batched := s.scanBatch(ctx, logger, window)
+		for _, commit := range window {
+			status, commitFindings, err := s.scanCommit(ctx, logger, redactor, scanned, commit, batched)
+			result.Commits = append(result.Commits, status)
+			if err != nil {
+				if s.FailOnError {
+					return result, fmt.Errorf("can't scan commit %s: %w", commit.Hash, err)
+				}
+				scanned.failed[commit.Hash] = err
+			}
+
+			scanned.add(commit.Hash, commitFindings)
+			found = append(found, s.filter(commitFindings)...)
+		}
+	}
+
+	if err := <-walked; err != nil {
+		return nil, fmt.Errorf("can't create diff: %w", err)
+	}
+	if len(result.Commits) == 0 {
+		return nil, ErrNoCommits
+	}
+
+	logger.Info("scanned commits", "count", len(result.Commits))
+
+	// Fingerprints are taken from the values as returned, mask them
+	// before any report is written.
+	result.Secrets = findings.Track(found, result.Hashes())
+	for _, secret := range result.Secrets {
+		secret.Value = redactor.Mask(secret.Value)
+	}
+	scanPolicy.Apply(result.Secrets)
+	result.ExitCode = scanPolicy.ExitCode(result.Secrets)
+	result.Finished = time.Now()
+
+	var errs []error
+	for _, reporter := range s.Reporters {
+		if err := reporter.Report(ctx, result); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return result, fmt.Errorf("can't report: %w", err)
+	}
+
+	return result, nil
+}
+
+// receive waits for the next commit and up to n-1 more, it returns nil
+// once commits is closed.
+func receive(commits <-chan git.Commit, n int) []git.Commit {
+	commit, ok := <-commits
+	if !ok {
+		return nil
+	}
+
+	window := []git.Commit{commit}
+	for len(window) < n {
+		commit, ok := <-commits
+		if !ok {
+			break
+		}
+		window = append(window, commit)
+	}
+
+	return window
+}
+
+// scanBatch scans the commits of the window having changed lines in one
+// go when Engine is a BatchEngine. The results are keyed by commit hash,
+// the commits missing from them are scanned one by one.
+func (s *Scanner) scanBatch(ctx context.Context, logger *slog.Logger, window []git.Commit) map[string]entro.BatchResult {
+	batchEngine, ok := s.Engine.(BatchEngine)
+	if !ok || len(window) < 2 {
+		return nil
+	}
+
+	var hashes []string
+	var scanReqs []*entro.ScanReq
+	for _, commit := range window {
+		data := commit.String()
+		if strings.TrimSpace(data) == "" {
+			continue
+		}
+		hashes = append(hashes, commit.Hash)
+		scanReqs = append(scanReqs, s.request(commit, data))
+	}
+	if len(scanReqs) < 2 {
+		return nil
+	}
+
+	logger.Info("scanning commits in batches", "count", len(scanReqs))
+
+	batched := make(map[string]entro.BatchResult, len(scanReqs))
+	for i, result := range batchEngine.ScanBatch(ctx, scanReqs) {
+		batched[hashes[i]] = result
+	}
+
+	return batched
+}
+
+// request returns the scan request of the commit data.
+func (s *Scanner) request(commit git.Commit, data string) *entro.ScanReq {
+	scanReq := &entro.ScanReq{Data: data}
+	if s.Provenance != nil {
+		scanReq.Provenance = &entro.Provenance{
+			Repository:  s.Provenance.Repository,
+			Commit:      commit.Hash,
+			Author:      commit.Author,
+			Files:       commit.Files(),
+			PullRequest: s.Provenance.PullRequest,
+		}
+	}
+
+	return scanReq
+}
+
+func (s *Scanner) scanCommit(ctx context.Context, logger *slog.Logger, redactor *redact.Redactor, scanned *scannedFiles, commit git.Commit, batched map[string]entro.BatchResult) (report.Commit, []findings.Finding, error) {
+	status := report.Commit{Hash: commit.Hash, Parent: commit.Parent, Files: commit.ChangedFiles()}
+	logger = logger.With("commit", commit.Hash)
+
+	duplicates, err := scanned.fanOut(commit)
+	if err != nil {
+		logger.Error("scan failed", "error", err)
+		status.Status = report.StatusError
+		status.Error = err.Error()
+
+		return status, nil, err
+	}
+
+	data := commit.String()
+	if strings.TrimSpace(data) == "" {
+		if len(commit.Diff.Duplicates) == 0 {
+			logger.Info("skipping commit, no changed lines")
+			status.Status = report.StatusSkipped
+
+			return status, nil, nil
+		}
+
+		logger.Info("commit changes were scanned with other commits already", "files", len(commit.Diff.Duplicates))
+		status.Findings = len(duplicates)
+		status.Status = report.StatusClean
+		if len(duplicates) > 0 {
+			status.Status = report.StatusFindings
+		}
+
+		return status, duplicates, nil
+	}
+
+	if s.Group != nil {
+		defer s.Group("Scanning commit " + commit.Hash)()
+	}
+	logger.Info("scanning commit", "files", len(status.Files))
+
+	var resp *entro.ScanResp
+	if result, ok := batched[commit.Hash]; ok {
+		resp, err = result.Resp, result.Err
+	} else {
+		resp, err = s.Engine.Scan(ctx, s.request(commit, data))
+	}
+	if err != nil {
+		logger.Error("scan failed", "error", err)
+		status.Status = report.StatusError
+		status.Error = err.Error()
+
+		return status, nil, err
+	}
+
+	// The engine is expected to mask values, don't rely on it.
+	for _, res := range resp.Results {
+		redactor.Add(res.Value)
+	}
+
+	status.RequestID = resp.RequestID
+	status.Findings = resp.TotalCount + len(duplicates)
+	status.Status = report.StatusClean
+
+	if status.Findings == 0 {
+		logger.Info("no secrets found", "request_id", resp.RequestID)
+
+		return status, nil, nil
+	}
+
+	status.Status = report.StatusFindings
+	logger.Info("found secrets", "count", resp.TotalCount, "duplicates", len(duplicates), "request_id", resp.RequestID)
+
+	var lines []string
+	if s.Verifier != nil {
+		lines = strings.Split(data, "\n")
+	}
+
+	found := duplicates
+	for _, res := range resp.Results {
+		loc, err := commit.Locate(res.Line)
+		if err != nil {
+			logger.Warn("can't map finding to a file", "line", res.Line, "error", err)
+
+			continue
+		}
+		f := findings.Finding{
+			Origin: res.Origin,
+			Value:  res.Value,
+			File:   loc.File,
+			Line:   loc.Line,
+			Commit: commit.Hash,
+			Side:   loc.Side,
+		}
+		if s.Verifier != nil {
+			f.Verification = s.verify(ctx, logger, redactor, scanned, res, lines)
+		}
+		found = append(found, f)
+	}
+
+	return status, found, nil
+}
+
+// verify checks the secret of the result, once per run. The engine may
+// mask the value, the secret is then recovered from the scanned line.
+func (s *Scanner) verify(ctx context.Context, logger *slog.Logger, redactor *redact.Redactor, scanned *scannedFiles, res entro.ScanResult, lines []string) findings.Verification {
+	var line string
+	if res.Line >= 1 && res.Line <= len(lines) {
+		line = lines[res.Line-1]
+	}
+
+	secret, ok := verify.Unmask(res.Value, line)
+	if !ok {
+		logger.Debug("can't recover the secret to verify", "origin", res.Origin, "line", res.Line)
+
+		return findings.VerificationUnknown
+	}
+	redactor.Add(secret)
+
+	key := res.Origin + "\x00" + secret
+	if verification, ok := scanned.verified[key]; ok {
+		return verification
+	}
+
+	verification, err := s.Verifier.Verify(ctx, res.Origin, secret)
+	if err != nil {
+		logger.Warn("can't verify secret", "origin", res.Origin, "error", err)
+		verification = findings.VerificationUnknown
+	}
+	logger.Info("verified secret", "origin", res.Origin, "verification", verification)
+
+	scanned.verified[key] = verification
+
+	return verification
+}
+
+// scannedFiles keeps the findings per scanned file, for the commits
+// changing a file the same way later on.
+type scannedFiles struct {
+	findings map[git.FileRef][]findings.Finding
+	failed   map[string]error
+	// verified holds the verification per origin and secret.
+	verified map[string]findings.Verification
+}
+
+func (sf *scannedFiles) add(commit string, found []findings.Finding) {
+	for _, f := range found {
+		ref := git.FileRef{Commit: commit, File: f.File}
+		sf.findings[ref] = append(sf.findings[ref], f)
+	}
+}
+
+// fanOut returns the findings of the duplicate files of commit, as found in
+// the commit they were scanned with.
+func (sf *scannedFiles) fanOut(commit git.Commit) ([]findings.Finding, error) {
+	var found []findings.Finding
+	for _, file := range commit.ChangedFiles() {
+		ref, ok := commit.Diff.Duplicates[file]
+		if !ok {
+			continue
+		}
+		if err := sf.failed[ref.Commit]; err != nil {
+			return nil, fmt.Errorf("can't scan %s, it changed like in commit %s: %w", file, ref.Commit, err)
+		}
+
+		for _, f := range sf.findings[ref] {
+			f.Commit = commit.Hash
+			f.File = file
+			found = append(found, f)
+		}
+	}
+
+	return found, nil
+}
+
+func (s *Scanner) filter(found []findings.Finding) []findings.Finding {
+	if len(s.Filters) == 0 {
+		return found
+	}
+
+	kept := found[:0]
+	for _, f := range found {
+		keep := true
+		for _, filter := range s.Filters {
+			if !filter(f) {
+				keep = false
+
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, f)
+		}
+	}
+
+	return kept
+}
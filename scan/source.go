@@ -0,0 +1,138 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/liminal-security/scan-action/git"
+	"github.com/liminal-security/scan-action/logging"
+)
+
+// Source provides the commits to scan, newest first. Walk calls fn with
+// every commit and stops at the first error fn returns, returning it.
+type Source interface {
+	Walk(ctx context.Context, fn func(git.Commit) error) error
+}
+
+// Commits is a Source of already diffed commits.
+type Commits []git.Commit
+
+func (c Commits) Walk(ctx context.Context, fn func(git.Commit) error) error {
+	for _, commit := range c {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(commit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// maxDeepen limits the fetches deepening a shallow checkout.
+const maxDeepen = 8
+
+// Repository is a Source diffing the commits of Range. When a shallow
+// checkout lacks the history of a range with a base and Remote is set, it
+// is deepened from Remote, Deepen commits at first and doubling every
+// fetch, otherwise the walk fails with git.ErrShallow. A range that can't
+// be diffed for another reason, e.g. its base doesn't exist, fails the
+// walk too rather than scanning the whole history.
+//
+// When MergeBase is set the base of Range is the tip of a target branch,
+// the commits are diffed from its merge base with the head. If the merge
+// base can't be found, e.g. the checkout is too shallow, the commits not
+// reachable from the target tip are scanned instead.
+type Repository struct {
+	Differ    *git.Differ
+	Range     git.Range
+	MergeBase bool
+	Remote    string
+	Deepen    int
+	Logger    *slog.Logger
+}
+
+func (r *Repository) Walk(ctx context.Context, fn func(git.Commit) error) error {
+	logger := logging.OrDiscard(r.Logger)
+
+	rng := r.Range
+	depth := r.Deepen
+	if depth <= 0 {
+		depth = 50
+	}
+
+	if r.MergeBase && rng.Base != "" {
+		var err error
+		rng.Base, depth, err = r.mergeBase(ctx, logger, depth)
+		if err != nil {
+			return err
+		}
+	}
+
+	if rng.Base != "" {
+		logger.Info("scanning commit range", "base", rng.Base, "head", rng.Head)
+	}
+
+	if err := r.deepen(ctx, logger, rng, depth); err != nil {
+		return err
+	}
+
+	return r.Differ.Walk(ctx, rng, fn)
+}
+
+// mergeBase returns the merge base of the target tip and the head of the
+// range, deepening a shallow checkout like deepen does. It falls back to
+// the target tip when the merge base can't be found. The returned depth is
+// the one of the next fetch.
+func (r *Repository) mergeBase(ctx context.Context, logger *slog.Logger, depth int) (string, int, error) {
+	head := r.Range.Head
+	if head == "" {
+		head = "HEAD"
+	}
+
+	for i := 0; ; i++ {
+		base, err := r.Differ.MergeBase(r.Range.Base, head)
+		if err == nil {
+			logger.Debug("found merge base", "target", r.Range.Base, "head", head, "merge_base", base)
+			return base, depth, nil
+		}
+
+		if !errors.Is(err, git.ErrShallow) || r.Remote == "" || i == maxDeepen {
+			logger.Warn("can't find the merge base with the target branch, scanning the commits not on its tip instead",
+				"target", r.Range.Base, "head", head, "error", err)
+			return r.Range.Base, depth, nil
+		}
+
+		logger.Info("checkout is too shallow, fetching more history", "remote", r.Remote, "depth", depth, "reason", err)
+		if err := r.Differ.Deepen(ctx, r.Remote, depth); err != nil {
+			return "", 0, err
+		}
+		depth *= 2
+	}
+}
+
+// deepen fetches more history, depth commits at first, until rng is
+// complete.
+func (r *Repository) deepen(ctx context.Context, logger *slog.Logger, rng git.Range, depth int) error {
+	// Without a base there is no end to fetch up to.
+	if r.Remote == "" || rng.Base == "" {
+		return nil
+	}
+
+	for range maxDeepen {
+		err := r.Differ.Complete(rng)
+		if !errors.Is(err, git.ErrShallow) {
+			return nil
+		}
+
+		logger.Info("checkout is too shallow, fetching more history", "remote", r.Remote, "depth", depth, "reason", err)
+		if err := r.Differ.Deepen(ctx, r.Remote, depth); err != nil {
+			return err
+		}
+		depth *= 2
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package scan
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liminal-security/scan-action/git"
+)
+
+func TestRepositoryMissingBase(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("token: one\n"), 0o644))
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "a.txt"},
+		{"commit", "--quiet", "-m", "a"},
+	} {
+		cmd := exec.Command("/usr/bin/git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "%s", out)
+	}
+
+	differ, err := git.NewDiffer(dir, nil)
+	require.NoError(t, err)
+
+	// A force-pushed away base fails the walk, the history isn't scanned
+	// instead.
+	repository := &Repository{Differ: differ, Range: git.Range{Base: "cf79a0c4802ab43e6a247ecc1d68d0ba59998133"}}
+	var walked []string
+	err = repository.Walk(context.Background(), func(c git.Commit) error {
+		walked = append(walked, c.Hash)
+
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Empty(t, walked)
+}
@@ -0,0 +1,128 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/liminal-security/scan-action/findings"
+)
+
+// GitHub checks tokens with GET BaseURL/user.
+type GitHub struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func (g *GitHub) Check(ctx context.Context, secret string) (findings.Verification, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(g.BaseURL, "/")+"/user", nil)
+	if err != nil {
+		return findings.VerificationUnknown, fmt.Errorf("can't create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+secret)
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return findings.VerificationUnknown, fmt.Errorf("can't check GitHub token: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return statusOf(resp)
+}
+
+// slackInvalid are the auth.test errors of tokens that will never work.
+var slackInvalid = map[string]bool{
+	"invalid_auth":     true,
+	"not_authed":       true,
+	"account_inactive": true,
+	"token_revoked":    true,
+	"token_expired":    true,
+}
+
+// Slack checks tokens with POST BaseURL/auth.test.
+type Slack struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func (s *Slack) Check(ctx context.Context, secret string) (findings.Verification, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.BaseURL, "/")+"/auth.test", nil)
+	if err != nil {
+		return findings.VerificationUnknown, fmt.Errorf("can't create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+secret)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return findings.VerificationUnknown, fmt.Errorf("can't check Slack token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return findings.VerificationUnknown, fmt.Errorf("unexpected HTTP code %d", resp.StatusCode)
+	}
+
+	// Slack answers 200 for rejected tokens too.
+	var body struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return findings.VerificationUnknown, fmt.Errorf("can't decode response: %w", err)
+	}
+
+	switch {
+	case body.OK:
+		return findings.VerificationVerified, nil
+	case slackInvalid[body.Error]:
+		return findings.VerificationInvalid, nil
+	default:
+		return findings.VerificationUnknown, fmt.Errorf("auth.test failed: %s", body.Error)
+	}
+}
+
+// Basic checks user:password credentials, or URLs carrying them, with a
+// GET of URL using HTTP basic authentication. The credentials are only
+// ever sent to URL, never to the host of a found URL.
+type Basic struct {
+	URL    string
+	Client *http.Client
+}
+
+func (b *Basic) Check(ctx context.Context, secret string) (findings.Verification, error) {
+	user, password, ok := basicCredentials(secret)
+	if !ok {
+		return findings.VerificationUnknown, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.URL, nil)
+	if err != nil {
+		return findings.VerificationUnknown, fmt.Errorf("can't create request: %w", err)
+	}
+	req.SetBasicAuth(user, password)
+
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		return findings.VerificationUnknown, fmt.Errorf("can't check credentials: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return statusOf(resp)
+}
+
+func basicCredentials(secret string) (user, password string, ok bool) {
+	if u, err := url.Parse(secret); err == nil && u.User != nil {
+		password, ok = u.User.Password()
+
+		return u.User.Username(), password, ok
+	}
+
+	return strings.Cut(secret, ":")
+}
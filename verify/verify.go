@@ -0,0 +1,129 @@
+// Package verify checks whether found secrets are live by calling the
+// service they belong to.
+package verify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/liminal-security/scan-action/findings"
+)
+
+const (
+	// DefaultGitHubURL is the API the GitHub check calls by default.
+	DefaultGitHubURL = "https://api.github.com"
+	// DefaultSlackURL is the API the Slack check calls by default.
+	DefaultSlackURL = "https://slack.com/api"
+)
+
+// timeout of a single check.
+const timeout = 10 * time.Second
+
+// Check verifies one kind of secret.
+type Check interface {
+	Check(ctx context.Context, secret string) (findings.Verification, error)
+}
+
+// Rule routes the origins matching a path.Match style pattern to a check.
+type Rule struct {
+	Pattern string
+	Check   Check
+}
+
+// Endpoints are the base URLs of the checked services, empty ones default
+// to the public services. Basic has no default, credentials are only
+// checked when it is set.
+type Endpoints struct {
+	GitHub string
+	Slack  string
+	Basic  string
+}
+
+// Verifier verifies secrets with the check of the first rule matching
+// their origin.
+type Verifier struct {
+	Rules []Rule
+}
+
+// New returns a verifier for GitHub and Slack tokens and, when
+// endpoints.Basic is set, HTTP basic credentials. A nil client defaults to
+// one timing out after 10 seconds.
+func New(endpoints Endpoints, client *http.Client) *Verifier {
+	if client == nil {
+		client = &http.Client{Timeout: timeout}
+	}
+	if endpoints.GitHub == "" {
+		endpoints.GitHub = DefaultGitHubURL
+	}
+	if endpoints.Slack == "" {
+		endpoints.Slack = DefaultSlackURL
+	}
+
+	v := &Verifier{
+		Rules: []Rule{
+			{Pattern: "GITHUB_*TOKEN*", Check: &GitHub{BaseURL: endpoints.GitHub, Client: client}},
+			{Pattern: "SLACK_*TOKEN*", Check: &Slack{BaseURL: endpoints.Slack, Client: client}},
+		},
+	}
+	if endpoints.Basic != "" {
+		v.Rules = append(v.Rules, Rule{Pattern: "*BASIC_AUTH*", Check: &Basic{URL: endpoints.Basic, Client: client}})
+	}
+
+	return v
+}
+
+// Verify checks the secret found with origin. Secrets no rule matches are
+// unknown.
+func (v *Verifier) Verify(ctx context.Context, origin, secret string) (findings.Verification, error) {
+	for _, rule := range v.Rules {
+		if ok, _ := path.Match(rule.Pattern, origin); ok {
+			return rule.Check.Check(ctx, secret)
+		}
+	}
+
+	return findings.VerificationUnknown, nil
+}
+
+// Unmask finds the secret value in line, the text it was found in. Values
+// masked by the scan API keep their leading and trailing characters and
+// replace every other one with a *. It returns false if the value can't be
+// recovered.
+func Unmask(value, line string) (string, bool) {
+	first := strings.IndexByte(value, '*')
+	if first < 0 {
+		return value, value != ""
+	}
+	last := strings.LastIndexByte(value, '*')
+
+	prefix, suffix := value[:first], value[last+1:]
+	if prefix == "" && suffix == "" {
+		return "", false
+	}
+
+	re, err := regexp.Compile(fmt.Sprintf(`%s\S{%d}%s`, regexp.QuoteMeta(prefix), last+1-first, regexp.QuoteMeta(suffix)))
+	if err != nil {
+		return "", false
+	}
+
+	secret := re.FindString(line)
+
+	return secret, secret != ""
+}
+
+// statusOf maps the HTTP status of an authenticated request to the
+// verification of its credentials.
+func statusOf(resp *http.Response) (findings.Verification, error) {
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return findings.VerificationVerified, nil
+	case resp.StatusCode == http.StatusUnauthorized:
+		return findings.VerificationInvalid, nil
+	default:
+		return findings.VerificationUnknown, fmt.Errorf("unexpected HTTP code %d", resp.StatusCode)
+	}
+}